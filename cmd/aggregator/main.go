@@ -4,10 +4,13 @@ import (
 	"flag"
 
 	"github.com/LucaChot/pronto/src/aggregate"
+	"github.com/LucaChot/pronto/src/schema"
 
 	log "github.com/sirupsen/logrus"
 )
 
+var metricSchema = flag.String("metrics", schema.Default.String(), "comma separated metric dimensions, must match the remote schedulers")
+
 func init() {
 	flag.Parse()
 
@@ -19,6 +22,13 @@ func init() {
 
 
 func main() {
-    agg := aggregate.New()
+	s, err := schema.Parse(*metricSchema)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID FLAG")
+	}
+
+    agg := aggregate.New(s)
     agg.Aggregate()
 }
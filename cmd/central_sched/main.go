@@ -4,10 +4,27 @@ import (
 	"flag"
 
 	"github.com/LucaChot/pronto/src/central"
+	"github.com/LucaChot/pronto/src/schema"
 
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	signalTTL        = flag.Duration("signal-ttl", central.DefaultConfig().SignalTTL, "age after which a node's job signal is considered stale")
+	stalePolicy      = flag.String("stale-policy", string(central.DefaultConfig().StalePolicy), "how stale signals are treated: expire or decay")
+	penaltyMode      = flag.String("penalty-mode", string(central.DefaultConfig().PenaltyMode), "load added to a node's signal per placement: none, fixed or projected")
+	penaltyIncrement = flag.Float64("penalty-increment", central.DefaultConfig().PenaltyIncrement, "fixed signal penalty per placement")
+	profilesPath     = flag.String("profiles", "", "YAML file of scheduler profiles, overrides the single profile set by the flags below")
+	scorer           = flag.String("scorer", central.DefaultProfile().Scorer, "node scoring strategy: argmin, power-of-two, weighted-random, softmax or fit-blend")
+	temperature      = flag.Float64("softmax-temperature", central.DefaultProfile().SoftmaxTemperature, "temperature of the softmax scorer")
+	fitWeight        = flag.Float64("fit-weight", central.DefaultProfile().FitWeight, "weight of requested-resource fit against signal in the fit-blend scorer")
+	leaderElect      = flag.Bool("leader-elect", central.DefaultConfig().LeaderElect, "only schedule pods while holding the leader Lease")
+	leaseName        = flag.String("lease-name", central.DefaultConfig().LeaseName, "name of the leader election Lease")
+	leaseNamespace   = flag.String("lease-namespace", central.DefaultConfig().LeaseNamespace, "namespace of the leader election Lease")
+	threshold        = flag.Float64("threshold", central.DefaultConfig().Threshold, "signal threshold pushed to remote schedulers, 0 leaves their own")
+	metricSchema     = flag.String("metrics", schema.Default.String(), "comma separated metric dimensions reported by remote schedulers")
+)
+
 func init() {
 	flag.Parse()
 
@@ -20,6 +37,52 @@ func init() {
 
 func main() {
 
-	ctl := central.New()
-    ctl.Schedule()
+	config := central.DefaultConfig()
+	config.SignalTTL = *signalTTL
+
+	policy, err := central.ParseStalePolicy(*stalePolicy)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID FLAG")
+	}
+	config.StalePolicy = policy
+
+	mode, err := central.ParsePenaltyMode(*penaltyMode)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID FLAG")
+	}
+	config.PenaltyMode = mode
+	config.PenaltyIncrement = *penaltyIncrement
+	if *profilesPath != "" {
+		profiles, err := central.LoadProfiles(*profilesPath)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"ERROR": err,
+			}).Fatal("INVALID PROFILES")
+		}
+		config.Profiles = profiles
+	} else {
+		profile := central.DefaultProfile()
+		profile.Scorer = *scorer
+		profile.SoftmaxTemperature = *temperature
+		profile.FitWeight = *fitWeight
+		config.Profiles = []central.Profile{profile}
+	}
+	config.Threshold = *threshold
+
+	config.Schema, err = schema.Parse(*metricSchema)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID FLAG")
+	}
+	config.LeaderElect = *leaderElect
+	config.LeaseName = *leaseName
+	config.LeaseNamespace = *leaseNamespace
+
+	ctl := central.New(config)
+    ctl.Run()
 }
@@ -3,11 +3,38 @@ package main
 import (
 	"flag"
 
+	"github.com/LucaChot/pronto/src/fpca"
+	"github.com/LucaChot/pronto/src/metrics"
 	"github.com/LucaChot/pronto/src/remote"
+	"github.com/LucaChot/pronto/src/schema"
 
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	metricSchema      = flag.String("metrics", schema.Default.String(), "comma separated metric dimensions to collect, must match the aggregator")
+	sampleInterval    = flag.Duration("sample-interval", metrics.DefaultConfig().Interval, "time between metric samples")
+	window            = flag.Int("window", metrics.DefaultConfig().Window, "samples per window passed to FPCA")
+	hop               = flag.Int("hop", metrics.DefaultConfig().Hop, "samples between consecutive windows, less than -window overlaps them")
+	reportInterval    = flag.Duration("report-interval", remote.DefaultConfig().ReportInterval, "time between job signal calculations")
+	rank              = flag.Int("rank", fpca.DefaultConfig().Rank, "rank of the FPCA subspace, the starting rank with -adaptive-rank")
+	adaptiveRank      = flag.Bool("adaptive-rank", fpca.DefaultConfig().Adaptive, "adapt the FPCA rank to the data, between 1 and the metric dimension")
+	rankAlpha         = flag.Float64("rank-alpha", fpca.DefaultConfig().Alpha, "impact of the last singular value below which the rank shrinks")
+	rankBeta          = flag.Float64("rank-beta", fpca.DefaultConfig().Beta, "impact of the last singular value above which the rank grows")
+	historyWeight     = flag.Float64("history-weight", fpca.DefaultConfig().Weights.HistoryWeight, "scale of the local FPCA estimate per merged window, below 1 forgets")
+	newDataWeight     = flag.Float64("new-data-weight", fpca.DefaultConfig().Weights.NewDataWeight, "scale of each new window merged into the local FPCA estimate")
+	halfLife          = flag.Duration("half-life", fpca.DefaultConfig().HalfLife, "sample time over which the local FPCA estimate loses half its weight, replaces -history-weight when positive")
+	subspaceEpsilon   = flag.Float64("subspace-epsilon", fpca.DefaultConfig().Epsilon, "largest principal angle in radians the FPCA subspace can move before it is merged with the aggregate")
+	recordPath        = flag.String("record", "", "CSV file every metric sample is recorded to")
+	replayPath        = flag.String("replay", "", "CSV trace replayed instead of collecting live metrics")
+	replaySpeed       = flag.Float64("replay-speed", metrics.DefaultConfig().ReplaySpeed, "replay speed relative to the recording, 0 replays without pausing")
+	diskMaxThroughput = flag.Float64("disk-max-throughput", metrics.DefaultConfig().DiskMaxThroughput, "disk throughput in bytes/s treated as fully utilised, 0 calibrates against the observed peak")
+	netDevPath        = flag.String("net-dev", metrics.DefaultConfig().NetDevPath, "interface counters of the node's network namespace, e.g. /proc/1/net/dev of the host's /proc")
+	sysClassNetPath   = flag.String("sys-class-net", metrics.DefaultConfig().SysClassNetPath, "network devices of the node's network namespace, e.g. class/net of the host's /sys")
+	cgroupRoot        = flag.String("cgroup-root", metrics.DefaultConfig().CgroupRoot, "mount point of the node's cgroup v2 hierarchy, read for per-pod usage")
+	networkCapacity   = flag.Float64("network-capacity", metrics.DefaultConfig().NetworkCapacity, "combined capacity in bytes/s of all physical links, applied to receive and transmit alike, 0 sums the speeds in /sys/class/net")
+)
+
 func init() {
 	flag.Parse()
 
@@ -18,6 +45,53 @@ func init() {
 }
 
 func main() {
-	rmt := remote.New()
+	config := remote.DefaultConfig()
+	config.ReportInterval = *reportInterval
+	config.Metrics.Interval = *sampleInterval
+	config.Metrics.Window = *window
+	config.Metrics.Hop = *hop
+	config.FPCA.Rank = *rank
+	config.FPCA.Adaptive = *adaptiveRank
+	config.FPCA.Alpha = *rankAlpha
+	config.FPCA.Beta = *rankBeta
+	config.FPCA.Weights.HistoryWeight = *historyWeight
+	config.FPCA.Weights.NewDataWeight = *newDataWeight
+	config.FPCA.HalfLife = *halfLife
+	config.FPCA.Epsilon = *subspaceEpsilon
+	config.Metrics.RecordPath = *recordPath
+	config.Metrics.ReplayPath = *replayPath
+	config.Metrics.ReplaySpeed = *replaySpeed
+	config.Metrics.DiskMaxThroughput = *diskMaxThroughput
+	config.Metrics.NetworkCapacity = *networkCapacity
+	config.Metrics.NetDevPath = *netDevPath
+	config.Metrics.SysClassNetPath = *sysClassNetPath
+	config.Metrics.CgroupRoot = *cgroupRoot
+
+	s, err := schema.Parse(*metricSchema)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID FLAG")
+	}
+	config.Metrics.Schema = s
+
+	if err := config.Metrics.Validate(); err != nil {
+		log.WithFields(log.Fields{
+			"ERROR":     err,
+			"AVAILABLE": metrics.Registered(),
+		}).Fatal("INVALID FLAG")
+	}
+	if err := config.FPCA.Validate(s); err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID FLAG")
+	}
+	if err := config.Validate(); err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID FLAG")
+	}
+
+	rmt := remote.New(config)
     rmt.Schedule()
 }
@@ -32,26 +32,32 @@ import (
 	log "github.com/sirupsen/logrus"
     mt "github.com/LucaChot/pronto/src/matrix"
 	pb "github.com/LucaChot/pronto/src/message"
+	"github.com/LucaChot/pronto/src/schema"
 )
 
 const (
     MAXWAITING = 20
-    D = 2
     R = 2
 )
 
 type Aggregator struct {
+    /* Contributions must use this metric schema */
+    schema schema.Schema
+
     matrices chan *mat.Dense
     aggregate atomic.Pointer[mat.Dense]
+    /* Drift of the aggregate caused by the last contribution */
+    drift atomic.Pointer[mt.Drift]
     pb.UnimplementedAggregateMergeServer
 }
 
-func New() (*Aggregator) {
+func New(s schema.Schema) (*Aggregator) {
     agg := Aggregator {
+        schema: s,
         matrices: make(chan *mat.Dense, MAXWAITING),
     }
 
-    agg.aggregate.Store(mat.NewDense(D, R, nil))
+    agg.aggregate.Store(mat.NewDense(s.D(), min(R, s.D()), nil))
 
     agg.startAggregateServer()
 
@@ -59,28 +65,56 @@ func New() (*Aggregator) {
 }
 
 /*
-TODO: Check whether the remote schedulers expose the matrix with ranks that are
-less than or greater than r?
-I.e. Check if there may be a mismatch in the rank
+Remote schedulers with adaptive rank contribute matrices of different ranks.
+The aggregate keeps the larger of its own rank and the contribution's, capped
+at the metric dimension, so that no node's components are cut off; each node
+merges the aggregate back down to its own rank
 */
 func (agg *Aggregator) Aggregate()  {
     for {
         inUSigma := <- agg.matrices
-        _, r := inUSigma.Dims()
+        _, inR := inUSigma.Dims()
 
         /* Uses sync/atomic pointer */
         currUSigma := agg.aggregate.Load()
-
-        U, Sigma := mt.AggMerge(currUSigma, inUSigma, r)
+        _, currR := currUSigma.Dims()
+
+        r := min(max(currR, inR), agg.schema.D())
+        U, Sigma, err := mt.AggMerge(currUSigma, inUSigma, r)
+        if err != nil {
+            /* Drop the contribution and keep serving the current aggregate */
+            log.WithFields(log.Fields{
+                "RANK":    r,
+                "IN RANK": inR,
+                "ERROR":   err,
+            }).Warn("DROPPED CONTRIBUTION")
+            continue
+        }
 
         var newUSigma mat.Dense
         newUSigma.Mul(U, Sigma)
 
         agg.aggregate.Store(&newUSigma)
-        log.Debug("PERFORMED AGGREGATION")
+
+        fields := log.Fields{
+            "RANK":    r,
+            "IN RANK": inR,
+        }
+        if drift, err := mt.SubspaceDrift(currUSigma, &newUSigma); err == nil {
+            agg.drift.Store(&drift)
+            fields["CHORDAL"] = drift.Chordal
+            fields["PROJECTION"] = drift.Projection
+            fields["EXPLAINED VARIANCE"] = drift.ExplainedVariance
+        }
+        log.WithFields(fields).Debug("PERFORMED AGGREGATION")
     }
 }
 
+/* Drift of the aggregate caused by the last contribution, nil before the first */
+func (agg *Aggregator) Drift() *mt.Drift {
+    return agg.drift.Load()
+}
+
 /*
 Removed the need to create a new Sigma matrix by passing in the product
 _, inc := inUSigma.Dims()
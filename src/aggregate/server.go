@@ -2,12 +2,16 @@ package aggregate
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"net"
 
 	pb "github.com/LucaChot/pronto/src/message"
 	log "github.com/sirupsen/logrus"
 	"gonum.org/v1/gonum/mat"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 
@@ -43,6 +47,13 @@ Research into Golang's memory model
 */
 func (agg *Aggregator) RequestAggMerge(ctx context.Context, in *pb.DenseMatrix) (*pb.DenseMatrix, error) {
     log.Debug("RECEIVED AGGREGATE REQUEST")
+    if err := agg.validate(in); err != nil {
+        log.WithFields(log.Fields{
+            "ERR": err,
+        }).Debug("REJECTED AGGREGATE REQUEST")
+        return nil, status.Error(codes.InvalidArgument, err.Error())
+    }
+
     inUSigma := mat.NewDense(int(in.Rows), int(in.Cols), in.Data)
     agg.matrices<- inUSigma
 
@@ -53,9 +64,36 @@ func (agg *Aggregator) RequestAggMerge(ctx context.Context, in *pb.DenseMatrix)
         Rows: int64(rows),
         Cols: int64(cols),
         Data: aggUSigma.RawMatrix().Data,
+        SchemaVersion: agg.schema.Version(),
     }, nil
 }
 
+/*
+Rejects contributions that were collected under a different metric schema,
+whose shape does not match their data or that hold NaN or infinite values
+*/
+func (agg *Aggregator) validate(in *pb.DenseMatrix) error {
+    if in.SchemaVersion != agg.schema.Version() {
+        return fmt.Errorf("schema version %d does not match aggregator schema %d (%s)",
+            in.SchemaVersion, agg.schema.Version(), agg.schema)
+    }
+    if in.Rows != int64(agg.schema.D()) {
+        return fmt.Errorf("matrix has %d rows, schema has %d dimensions", in.Rows, agg.schema.D())
+    }
+    if in.Cols <= 0 || in.Cols > in.Rows {
+        return fmt.Errorf("rank %d is outside 1 to %d", in.Cols, in.Rows)
+    }
+    if int64(len(in.Data)) != in.Rows*in.Cols {
+        return fmt.Errorf("matrix of %dx%d carries %d values", in.Rows, in.Cols, len(in.Data))
+    }
+    for i, v := range in.Data {
+        if math.IsNaN(v) || math.IsInf(v, 0) {
+            return fmt.Errorf("value %v at (%d, %d) is not finite", v, int64(i) / in.Cols, int64(i) % in.Cols)
+        }
+    }
+    return nil
+}
+
 /*
 Move Merge into Aggregate Server
 U, Sigma = pointer.read()
@@ -0,0 +1,125 @@
+package central
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+    /* How long a bound pod stays assumed while waiting for the informer */
+    AssumedPodTTL       = 30 * time.Second
+    assumeCleanupPeriod = time.Second
+)
+
+type assumedPod struct {
+    pod  *v1.Pod
+    node string
+
+    /* Zero while the bind is still in flight */
+    deadline time.Time
+}
+
+/*
+Optimistic cache of pods that have been assigned to a node but that the pod
+informer has not yet seen bound. Assumed pods count against their node in
+NodeInfo, so the HostPorts and ResourcesFit predicates of back-to-back
+decisions see each other's placements. The signal ranking does not read
+NodeInfo; the placement penalty, applied when a pod is assumed and reverted
+if its bind fails, makes in-flight pods count there
+*/
+type AssumeCache struct {
+    mu   sync.Mutex
+    pods map[string]*assumedPod
+    ttl  time.Duration
+}
+
+func NewAssumeCache() *AssumeCache {
+    return &AssumeCache{
+        pods: make(map[string]*assumedPod),
+        ttl:  AssumedPodTTL,
+    }
+}
+
+/* Periodically drops bound pods that the informer never confirmed */
+func (ac *AssumeCache) Run(stopCh <-chan struct{}) {
+    ticker := time.NewTicker(assumeCleanupPeriod)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-stopCh:
+            return
+        case <-ticker.C:
+            ac.cleanup(time.Now())
+        }
+    }
+}
+
+/* Records that Pod p is about to be bound to Node n */
+func (ac *AssumeCache) Assume(p *v1.Pod, n string) {
+    key, _ := cache.MetaNamespaceKeyFunc(p)
+
+    assumed := p.DeepCopy()
+    assumed.Spec.NodeName = n
+
+    ac.mu.Lock()
+    defer ac.mu.Unlock()
+    ac.pods[key] = &assumedPod{
+        pod:  assumed,
+        node: n,
+    }
+}
+
+/* Starts the expiry timer of Pod p once its bind has succeeded */
+func (ac *AssumeCache) FinishBinding(p *v1.Pod) {
+    key, _ := cache.MetaNamespaceKeyFunc(p)
+
+    ac.mu.Lock()
+    defer ac.mu.Unlock()
+    if ap, ok := ac.pods[key]; ok {
+        ap.deadline = time.Now().Add(ac.ttl)
+    }
+}
+
+/* Removes Pod p, either because its bind failed or it was observed bound */
+func (ac *AssumeCache) Forget(p *v1.Pod) {
+    key, _ := cache.MetaNamespaceKeyFunc(p)
+
+    ac.mu.Lock()
+    defer ac.mu.Unlock()
+    delete(ac.pods, key)
+}
+
+/* Returns the assumed pods on Node n */
+func (ac *AssumeCache) PodsOn(n string) []*v1.Pod {
+    ac.mu.Lock()
+    defer ac.mu.Unlock()
+
+    var pods []*v1.Pod
+    for _, ap := range ac.pods {
+        if ap.node == n {
+            pods = append(pods, ap.pod)
+        }
+    }
+    return pods
+}
+
+func (ac *AssumeCache) cleanup(now time.Time) {
+    ac.mu.Lock()
+    defer ac.mu.Unlock()
+
+    for key, ap := range ac.pods {
+        if ap.deadline.IsZero() || now.Before(ap.deadline) {
+            continue
+        }
+        delete(ac.pods, key)
+        log.WithFields(log.Fields{
+            "POD":  key,
+            "NODE": ap.node,
+        }).Debug("EXPIRED ASSUMED POD")
+    }
+}
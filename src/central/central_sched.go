@@ -1,30 +1,78 @@
 package central
 
 import (
-	"context"
 	"fmt"
-	"math"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	pb "github.com/LucaChot/pronto/src/message"
+	"github.com/LucaChot/pronto/src/schema"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/flowcontrol"
 )
 
+/* Tunables of the central scheduler */
+type Config struct {
+    /* Signals older than this are treated according to StalePolicy */
+    SignalTTL   time.Duration
+    StalePolicy StalePolicy
+
+    /* Load added to a node's signal for every pod placed on it */
+    PenaltyMode      PenaltyMode
+    PenaltyIncrement float64
+
+    /* Metric schema of the U·Sigma that remote schedulers report */
+    Schema schema.Schema
+
+    /* Profiles served, matched by spec.schedulerName */
+    Profiles []Profile
+
+    /* Threshold pushed to remote schedulers when they connect, 0 leaves theirs */
+    Threshold float64
+
+    /* Only the holder of the Lease schedules pods */
+    LeaderElect    bool
+    LeaseName      string
+    LeaseNamespace string
+}
+
+func DefaultConfig() Config {
+    return Config{
+        SignalTTL:   5 * time.Second,
+        StalePolicy: StaleExpire,
+        PenaltyMode: PenaltyFixed,
+        PenaltyIncrement: 0.05,
+        Schema: schema.Default,
+        Profiles: []Profile{DefaultProfile()},
+        LeaderElect: true,
+        LeaseName: "central-sched",
+        LeaseNamespace: "basic-sched",
+    }
+}
+
 type CentralScheduler struct {
-    mu          sync.Mutex
-    Name        string
+    mu          sync.RWMutex
     clientset   *kubernetes.Clientset
+    config      Config
 
-    nodeMap     map[string]int
-    nodeSignals []atomic.Uint64
+    informerFactory informers.SharedInformerFactory
+    nodeLister      corelisters.NodeLister
+    podIndexer      cache.Indexer
+    stopCh          chan struct{}
+
+    nodeSignals map[string]*nodeSignal
+    profiles    map[string]*schedProfile
+    queue       *SchedulingQueue
+    assumed     *AssumeCache
+    streams     *streamSet
 
     Bins        map[string]string
     pb.UnimplementedPodPlacementServer
@@ -44,20 +92,28 @@ func (ctl *CentralScheduler) SetClientset() {
 }
 
 /* Creates a new CentralScheduler */
-func New() *CentralScheduler {
+func New(config Config) *CentralScheduler {
 
     /* Initialise scheduler values */
 	ctl := &CentralScheduler{
-		Name: "pronto",
+        config: config,
+        stopCh: make(chan struct{}),
+        nodeSignals: make(map[string]*nodeSignal),
+        queue: NewSchedulingQueue(),
+        assumed: NewAssumeCache(),
+        streams: newStreamSet(),
     }
 
-    ctl.SetClientset()
-    ctl.findNodes()
-    ctl.nodeSignals = make([]atomic.Uint64, len(ctl.nodeMap))
-
-    for node := range(len(ctl.nodeMap)) {
-        ctl.nodeSignals[node].Store(math.Float64bits(1))
+    profiles, err := compileProfiles(config.Profiles)
+    if err != nil {
+        log.WithFields(log.Fields{
+            "ERROR": err,
+        }).Fatal("INVALID PROFILES")
     }
+    ctl.profiles = profiles
+
+    ctl.SetClientset()
+    ctl.startInformers()
 
     ctl.ctlStartPlacementServer()
 
@@ -65,75 +121,173 @@ func New() *CentralScheduler {
 }
 
 
-/* Returns the node of the first remote scheduler to respond to new pod */
-func (ctl *CentralScheduler) findNode() string {
-    var name string
-    var minSignal float64
-    minSignal = 1
+/*
+Returns the node that the scorer of Pod p's profile picks among the feasible
+nodes with a signal below the profile's threshold. If no node passes the
+predicates, the error explains why
+*/
+func (ctl *CentralScheduler) findNode(p *v1.Pod) (string, error) {
+    profile, ok := ctl.profiles[p.Spec.SchedulerName]
+    if !ok {
+        return "", fmt.Errorf("no profile for scheduler %q", p.Spec.SchedulerName)
+    }
+
+    feasible, err := ctl.filterNodes(p, profile.predicates, ctl.nodeNames())
+    if err != nil {
+        return "", err
+    }
 
-    for node, index := range ctl.nodeMap {
-        signal := math.Float64frombits(ctl.nodeSignals[index].Load())
-        if signal < minSignal {
-            minSignal = signal
-            name = node
+    ctl.mu.RLock()
+    now := time.Now()
+    candidates := make([]Candidate, 0, len(feasible))
+    for _, info := range feasible {
+        nodeSignal, ok := ctl.nodeSignals[info.Node.Name]
+        if !ok {
+            continue
         }
+        signal, _ := effectiveSignal(nodeSignal.Load(), now, ctl.config.SignalTTL, ctl.config.StalePolicy)
+        if signal < profile.threshold {
+            candidates = append(candidates, Candidate{
+                Info:   info,
+                Signal: signal,
+            })
+        }
+    }
+    ctl.mu.RUnlock()
+
+    if len(candidates) == 0 {
+        return "", nil
     }
 
+    chosen := profile.scorer.Pick(p, candidates)
+
     log.WithFields(log.Fields{
-        "NODE": name,
-        "JOB SIGNAL": minSignal,
+        "NODE": chosen.Name(),
+        "JOB SIGNAL": chosen.Signal,
+        "PROFILE": profile.name,
+        "SCORER": profile.scorer.Name(),
     }).Debug("FOUND NODE")
 
-    return name
+    return chosen.Name(), nil
 }
 
 /* Core Scheduling loop */
 func (ctl *CentralScheduler) Schedule() {
+    go ctl.queue.Run(ctl.stopCh)
+    go ctl.assumed.Run(ctl.stopCh)
 
-    /* Creates a watch interface for all pods that use this scheduler */
-	watch, _ := ctl.clientset.CoreV1().Pods("").Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("spec.schedulerName=%s,spec.nodeName=", ctl.Name),
-	})
-
-    /* Loops over all new pod events we detect */
-	for event := range watch.ResultChan() {
-        /* Ignore events where pods have been added */
-		if event.Type != "ADDED" {
-			continue
-		}
+    /* Pops pods from the scheduling queue until it is closed */
+    for {
+        qp, ok := ctl.queue.Pop()
+        if !ok {
+            return
+        }
+        ctl.scheduleOne(qp)
+    }
+}
 
-        start := time.Now().UTC()
+/* Attempts to place a single pod, returning it to the queue on failure */
+func (ctl *CentralScheduler) scheduleOne(qp *QueuedPod) {
+    start := time.Now().UTC()
 
-		p := event.Object.(*v1.Pod)
-		log.WithFields(log.Fields{
-			"namespace": p.Namespace,
-			"pod":       p.Name,
-		}).Debug("BEGIN POD SCHEDULE")
+    p := qp.Pod
+    log.WithFields(log.Fields{
+        "namespace": p.Namespace,
+        "pod":       p.Name,
+        "attempts":  qp.Attempts,
+    }).Debug("BEGIN POD SCHEDULE")
 
+    /* Find a node to place the pod */
+    node, err := ctl.findNode(p)
+    if err == nil && node == "" {
+        err = fmt.Errorf("no node reported a job signal below the profile threshold")
+    }
+    if err != nil {
+        log.WithFields(log.Fields{
+            "pod":    p.Name,
+            "reason": err,
+        }).Debug("FAILED TO FIND SUITABLE NODE")
 
-        /* Find a node to place the pod */
-        node := ctl.findNode()
-        if node == "" {
-            log.Debug("FAILED TO FIND SUITABLE NODE")
-            continue
+        ctl.queue.AddUnschedulable(qp)
+        if err := ctl.setUnschedulableCondition(p, err.Error()); err != nil {
+            log.WithFields(log.Fields{
+                "err": err,
+            }).Debug("FAILED TO UPDATE POD CONDITION")
         }
+        return
+    }
+
+    /*
+    Assume the pod on the node and penalise its signal before binding, so the
+    next decision already sees it in both the predicates and the ranking,
+    then bind in the background
+    */
+    ctl.assumed.Assume(p, node)
+    penalty := ctl.applyPenalty(p, node)
+    go ctl.bind(qp, node, start, penalty)
+}
+
+/* Binds a queued pod to Node n and reports the outcome */
+func (ctl *CentralScheduler) bind(qp *QueuedPod, n string, start time.Time, penalty float64) {
+    p := qp.Pod
 
-        ctl.placePodToNode(p, node)
+    err := ctl.placePodToNode(p, n)
+    end := time.Now().UTC()
 
-        /* Collect information for event */
-		end := time.Now().UTC()
-        nanosecondsSpent := end.Sub(start).Nanoseconds()
-        annotations := map[string]string{
-            "scheduler/nanoseconds": fmt.Sprintf("%d", nanosecondsSpent),
+    if err != nil {
+        ctl.assumed.Forget(p)
+        ctl.revertPenalty(n, penalty)
+
+        log.WithFields(log.Fields{
+            "pod":  p.Name,
+            "node": n,
+            "err":  err,
+        }).Debug("FAILED TO BIND POD")
+
+        /* The pod was deleted or bound by someone else, nothing to retry */
+        if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+            ctl.queue.Done(qp)
+            return
         }
 
-        /* Creates a new event alerting the binding of the pod */
-        err := ctl.createSchedEvent(p, node, end, annotations)
-        if err != nil {
+        if isTransientError(err) {
+            /* placePodToNode ran out of retries, try again after a backoff */
+            ctl.queue.AddBackoff(qp)
+        } else {
+            /*
+            Permanent errors, e.g. Forbidden or Invalid, fail the same way on
+            every attempt, so the pod is dropped with a single event
+            */
+            log.WithFields(log.Fields{
+                "pod":  p.Name,
+                "node": n,
+                "err":  err,
+            }).Warn("DROPPED POD AFTER PERMANENT BIND FAILURE")
+            ctl.queue.Done(qp)
+        }
+        if err := ctl.createFailedSchedEvent(p, n, end, err); err != nil {
             log.WithFields(log.Fields{
                 "err": err,
             }).Debug("FAILED TO CREATE EVENT")
         }
-	}
-}
+        return
+    }
+
+    ctl.assumed.FinishBinding(p)
+    ctl.queue.Done(qp)
+    ctl.notifyPlacement(p, n, end)
+
+    /* Collect information for event */
+    nanosecondsSpent := end.Sub(start).Nanoseconds()
+    annotations := map[string]string{
+        "scheduler/nanoseconds": fmt.Sprintf("%d", nanosecondsSpent),
+    }
 
+    /* Creates a new event alerting the binding of the pod */
+    err = ctl.createSchedEvent(p, n, end, annotations)
+    if err != nil {
+        log.WithFields(log.Fields{
+            "err": err,
+        }).Debug("FAILED TO CREATE EVENT")
+    }
+}
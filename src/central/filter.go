@@ -0,0 +1,357 @@
+package central
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+/* Snapshot of a node and the pods currently assigned to it */
+type NodeInfo struct {
+    Node *v1.Node
+    Pods []*v1.Pod
+}
+
+/*
+A Predicate decides whether a pod may be placed on a node. Filter returns nil
+when the pod fits, otherwise a short reason that is reported back to the user
+*/
+type Predicate interface {
+    Name() string
+    Filter(p *v1.Pod, n *NodeInfo) error
+}
+
+/* Returned when no node passes every predicate */
+type FitError struct {
+    NumNodes int
+    Reasons  map[string]int
+}
+
+func (e *FitError) Error() string {
+    reasons := make([]string, 0, len(e.Reasons))
+    for reason, count := range e.Reasons {
+        reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
+    }
+    sort.Strings(reasons)
+    return fmt.Sprintf("0/%d nodes are available: %s", e.NumNodes, strings.Join(reasons, ", "))
+}
+
+/* Predicates applied to every pod, in order */
+func DefaultPredicates() []Predicate {
+    return []Predicate{
+        NodeSelectorPredicate{},
+        NodeAffinityPredicate{},
+        TaintTolerationPredicate{},
+        HostPortsPredicate{},
+        ResourcesFitPredicate{},
+    }
+}
+
+/* Looks up predicates by their Name, returning all defaults for no names */
+func PredicatesByName(names []string) ([]Predicate, error) {
+    defaults := DefaultPredicates()
+    if len(names) == 0 {
+        return defaults, nil
+    }
+
+    byName := make(map[string]Predicate, len(defaults))
+    for _, pred := range defaults {
+        byName[pred.Name()] = pred
+    }
+
+    predicates := make([]Predicate, 0, len(names))
+    for _, name := range names {
+        pred, ok := byName[name]
+        if !ok {
+            return nil, fmt.Errorf("unknown filter %q", name)
+        }
+        predicates = append(predicates, pred)
+    }
+    return predicates, nil
+}
+
+/*
+Returns the nodes that pass every predicate for Pod p. If none do, the error
+summarises why each node was rejected
+*/
+func (ctl *CentralScheduler) filterNodes(p *v1.Pod, predicates []Predicate, nodes []string) ([]*NodeInfo, error) {
+    feasible := make([]*NodeInfo, 0, len(nodes))
+    fitErr := &FitError{
+        NumNodes: len(nodes),
+        Reasons:  make(map[string]int),
+    }
+
+    for _, name := range nodes {
+        info, err := ctl.nodeInfo(name)
+        if err != nil {
+            fitErr.Reasons["node(s) not found in cache"]++
+            continue
+        }
+
+        fits := true
+        for _, pred := range predicates {
+            if err := pred.Filter(p, info); err != nil {
+                fitErr.Reasons[err.Error()]++
+                fits = false
+                break
+            }
+        }
+
+        if fits {
+            feasible = append(feasible, info)
+        }
+    }
+
+    if len(feasible) == 0 {
+        return nil, fitErr
+    }
+    return feasible, nil
+}
+
+/* Node selector */
+type NodeSelectorPredicate struct{}
+
+func (NodeSelectorPredicate) Name() string { return "NodeSelector" }
+
+func (NodeSelectorPredicate) Filter(p *v1.Pod, n *NodeInfo) error {
+    if len(p.Spec.NodeSelector) == 0 {
+        return nil
+    }
+    if !labels.SelectorFromSet(p.Spec.NodeSelector).Matches(labels.Set(n.Node.Labels)) {
+        return fmt.Errorf("node(s) didn't match Pod's node selector")
+    }
+    return nil
+}
+
+/* Required node affinity */
+type NodeAffinityPredicate struct{}
+
+func (NodeAffinityPredicate) Name() string { return "NodeAffinity" }
+
+func (NodeAffinityPredicate) Filter(p *v1.Pod, n *NodeInfo) error {
+    affinity := p.Spec.Affinity
+    if affinity == nil || affinity.NodeAffinity == nil {
+        return nil
+    }
+    required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+    if required == nil {
+        return nil
+    }
+
+    /* Terms are ORed, requirements within a term are ANDed */
+    for _, term := range required.NodeSelectorTerms {
+        if matchNodeSelectorTerm(term, n.Node) {
+            return nil
+        }
+    }
+    return fmt.Errorf("node(s) didn't match Pod's node affinity")
+}
+
+func matchNodeSelectorTerm(term v1.NodeSelectorTerm, n *v1.Node) bool {
+    if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+        return false
+    }
+
+    if len(term.MatchExpressions) > 0 {
+        selector, err := nodeSelectorRequirementsAsSelector(term.MatchExpressions)
+        if err != nil || !selector.Matches(labels.Set(n.Labels)) {
+            return false
+        }
+    }
+
+    if len(term.MatchFields) > 0 {
+        selector, err := nodeSelectorRequirementsAsSelector(term.MatchFields)
+        if err != nil || !selector.Matches(labels.Set{"metadata.name": n.Name}) {
+            return false
+        }
+    }
+
+    return true
+}
+
+func nodeSelectorRequirementsAsSelector(reqs []v1.NodeSelectorRequirement) (labels.Selector, error) {
+    selector := labels.NewSelector()
+    for _, req := range reqs {
+        var op selection.Operator
+        switch req.Operator {
+        case v1.NodeSelectorOpIn:
+            op = selection.In
+        case v1.NodeSelectorOpNotIn:
+            op = selection.NotIn
+        case v1.NodeSelectorOpExists:
+            op = selection.Exists
+        case v1.NodeSelectorOpDoesNotExist:
+            op = selection.DoesNotExist
+        case v1.NodeSelectorOpGt:
+            op = selection.GreaterThan
+        case v1.NodeSelectorOpLt:
+            op = selection.LessThan
+        default:
+            return nil, fmt.Errorf("%q is not a valid node selector operator", req.Operator)
+        }
+
+        r, err := labels.NewRequirement(req.Key, op, req.Values)
+        if err != nil {
+            return nil, err
+        }
+        selector = selector.Add(*r)
+    }
+    return selector, nil
+}
+
+/* Taints and tolerations */
+type TaintTolerationPredicate struct{}
+
+func (TaintTolerationPredicate) Name() string { return "TaintToleration" }
+
+func (TaintTolerationPredicate) Filter(p *v1.Pod, n *NodeInfo) error {
+    for i := range n.Node.Spec.Taints {
+        taint := &n.Node.Spec.Taints[i]
+        /* PreferNoSchedule taints are soft and never filter a node */
+        if taint.Effect != v1.TaintEffectNoSchedule && taint.Effect != v1.TaintEffectNoExecute {
+            continue
+        }
+        if !toleratesTaint(p.Spec.Tolerations, taint) {
+            return fmt.Errorf("node(s) had untolerated taint {%s: %s}", taint.Key, taint.Value)
+        }
+    }
+    return nil
+}
+
+func toleratesTaint(tolerations []v1.Toleration, taint *v1.Taint) bool {
+    for i := range tolerations {
+        if tolerations[i].ToleratesTaint(taint) {
+            return true
+        }
+    }
+    return false
+}
+
+/* Host ports */
+type HostPortsPredicate struct{}
+
+func (HostPortsPredicate) Name() string { return "HostPorts" }
+
+func (HostPortsPredicate) Filter(p *v1.Pod, n *NodeInfo) error {
+    wanted := hostPorts(p)
+    if len(wanted) == 0 {
+        return nil
+    }
+
+    for _, existing := range n.Pods {
+        for _, used := range hostPorts(existing) {
+            for _, want := range wanted {
+                if portsConflict(want, used) {
+                    return fmt.Errorf("node(s) didn't have free ports for the requested pod ports")
+                }
+            }
+        }
+    }
+    return nil
+}
+
+func hostPorts(p *v1.Pod) []v1.ContainerPort {
+    var ports []v1.ContainerPort
+    for _, c := range p.Spec.Containers {
+        for _, port := range c.Ports {
+            if port.HostPort <= 0 {
+                continue
+            }
+            ports = append(ports, port)
+        }
+    }
+    return ports
+}
+
+func portsConflict(a, b v1.ContainerPort) bool {
+    if a.HostPort != b.HostPort || protocolOf(a) != protocolOf(b) {
+        return false
+    }
+    return hostIPOf(a) == hostIPOf(b) || hostIPOf(a) == "0.0.0.0" || hostIPOf(b) == "0.0.0.0"
+}
+
+func protocolOf(port v1.ContainerPort) v1.Protocol {
+    if port.Protocol == "" {
+        return v1.ProtocolTCP
+    }
+    return port.Protocol
+}
+
+func hostIPOf(port v1.ContainerPort) string {
+    if port.HostIP == "" {
+        return "0.0.0.0"
+    }
+    return port.HostIP
+}
+
+/* Resource requests against node allocatable */
+type ResourcesFitPredicate struct{}
+
+func (ResourcesFitPredicate) Name() string { return "NodeResourcesFit" }
+
+func (ResourcesFitPredicate) Filter(p *v1.Pod, n *NodeInfo) error {
+    allocatable := n.Node.Status.Allocatable
+
+    if podLimit, ok := allocatable[v1.ResourcePods]; ok && int64(len(n.Pods)+1) > podLimit.Value() {
+        return fmt.Errorf("Too many pods")
+    }
+
+    requested := podRequests(p)
+    if len(requested) == 0 {
+        return nil
+    }
+
+    used := v1.ResourceList{}
+    for _, existing := range n.Pods {
+        addResourceList(used, podRequests(existing))
+    }
+
+    for name, req := range requested {
+        if req.IsZero() {
+            continue
+        }
+        free := allocatable[name].DeepCopy()
+        free.Sub(used[name])
+        if free.Cmp(req) < 0 {
+            return fmt.Errorf("Insufficient %s", name)
+        }
+    }
+    return nil
+}
+
+/*
+Effective requests of Pod p: the larger of the summed app containers and any
+single init container, plus the pod overhead
+*/
+func podRequests(p *v1.Pod) v1.ResourceList {
+    reqs := v1.ResourceList{}
+    for _, c := range p.Spec.Containers {
+        addResourceList(reqs, c.Resources.Requests)
+    }
+
+    for _, c := range p.Spec.InitContainers {
+        for name, q := range c.Resources.Requests {
+            if cur, ok := reqs[name]; !ok || q.Cmp(cur) > 0 {
+                reqs[name] = q.DeepCopy()
+            }
+        }
+    }
+
+    addResourceList(reqs, p.Spec.Overhead)
+    return reqs
+}
+
+func addResourceList(dst, src v1.ResourceList) {
+    for name, q := range src {
+        if cur, ok := dst[name]; ok {
+            cur.Add(q)
+            dst[name] = cur
+        } else {
+            dst[name] = q.DeepCopy()
+        }
+    }
+}
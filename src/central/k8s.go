@@ -5,37 +5,42 @@ import (
 	"fmt"
 	"time"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/util/retry"
 )
 
-func (ctl *CentralScheduler) findNodes() {
-	// TODO add informer to get the list of nodes
-	nodes, _ := ctl.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("%s!=%s", "node-role.kubernetes.io/control-plane", ""),
-	})
-	nMap := make(map[string]int)
-
-	for i, node := range nodes.Items {
-		nMap[node.Name] = i
-	}
+/*
+Binds Pod p to Node n, retrying transient API errors with exponential backoff
+*/
+func (ctl *CentralScheduler) placePodToNode(p *v1.Pod, n string) error {
+    binding := &v1.Binding{
+        ObjectMeta: metav1.ObjectMeta{
+            Name:      p.Name,
+            Namespace: p.Namespace,
+            UID:       p.UID,
+        },
+        Target: v1.ObjectReference{
+            APIVersion: "v1",
+            Kind:       "Node",
+            Name:       n,
+        },
+    }
 
-	ctl.nodeMap = nMap
+    return retry.OnError(retry.DefaultBackoff, isTransientError, func() error {
+        return ctl.clientset.CoreV1().Pods(p.Namespace).Bind(context.TODO(), binding, metav1.CreateOptions{})
+    })
 }
 
-
-/* Binds Pod p to Node n */
-func (ctl * CentralScheduler) placePodToNode(p *v1.Pod, n string) {
-		ctl.clientset.CoreV1().Pods(p.Namespace).Bind(context.TODO(), &v1.Binding{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      p.Name,
-				Namespace: p.Namespace,
-			},
-			Target: v1.ObjectReference{
-				APIVersion: "v1",
-				Kind:       "Node",
-				Name:       n,
-			},
-		}, metav1.CreateOptions{})
+/* Returns whether a failed API call is worth retrying */
+func isTransientError(err error) bool {
+    if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) ||
+        apierrors.IsTooManyRequests(err) || apierrors.IsInternalError(err) ||
+        apierrors.IsServiceUnavailable(err) || apierrors.IsUnexpectedServerError(err) {
+        return true
+    }
+    return utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err)
 }
 
 /* Creates Event detailing Binding */
@@ -46,8 +51,8 @@ func (ctl *CentralScheduler) createSchedEvent(p *v1.Pod, n string, eventTime tim
         Reason:         "Scheduled",
         EventTime:      metav1.NewMicroTime(eventTime),
         Type:           "Normal",
-        ReportingController: ctl.Name,
-        ReportingInstance: fmt.Sprintf("%s-dev-k8s-lc869-00", ctl.Name),
+        ReportingController: p.Spec.SchedulerName,
+        ReportingInstance: fmt.Sprintf("%s-dev-k8s-lc869-00", p.Spec.SchedulerName),
         InvolvedObject: v1.ObjectReference{
             Kind:      "Pod",
             Name:      p.Name,
@@ -61,3 +66,66 @@ func (ctl *CentralScheduler) createSchedEvent(p *v1.Pod, n string, eventTime tim
     }, metav1.CreateOptions{})
 	return err
 }
+
+/* Creates Event detailing why Pod p could not be bound to Node n */
+func (ctl *CentralScheduler) createFailedSchedEvent(p *v1.Pod, n string, eventTime time.Time, bindErr error) error {
+    _, err := ctl.clientset.CoreV1().Events(p.Namespace).Create(context.TODO(), &v1.Event{
+        Action:         "Binding",
+        Message:        fmt.Sprintf("Failed to bind %s/%s to %s: %v", p.Namespace, p.Name, n, bindErr),
+        Reason:         "FailedScheduling",
+        EventTime:      metav1.NewMicroTime(eventTime),
+        Type:           "Warning",
+        ReportingController: p.Spec.SchedulerName,
+        ReportingInstance: fmt.Sprintf("%s-dev-k8s-lc869-00", p.Spec.SchedulerName),
+        InvolvedObject: v1.ObjectReference{
+            Kind:      "Pod",
+            Name:      p.Name,
+            Namespace: p.Namespace,
+            UID:       p.UID,
+        },
+        ObjectMeta: metav1.ObjectMeta{
+            GenerateName: p.Name + "-",
+        },
+    }, metav1.CreateOptions{})
+	return err
+}
+
+/*
+Sets the PodScheduled=False condition on Pod p so users can see why it is still
+pending. The update is skipped if the condition already carries the message
+*/
+func (ctl *CentralScheduler) setUnschedulableCondition(p *v1.Pod, message string) error {
+    for _, cond := range p.Status.Conditions {
+        if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse &&
+            cond.Reason == v1.PodReasonUnschedulable && cond.Message == message {
+            return nil
+        }
+    }
+
+    updated := p.DeepCopy()
+    condition := v1.PodCondition{
+        Type:               v1.PodScheduled,
+        Status:             v1.ConditionFalse,
+        Reason:             v1.PodReasonUnschedulable,
+        Message:            message,
+        LastTransitionTime: metav1.Now(),
+    }
+
+    replaced := false
+    for i := range updated.Status.Conditions {
+        if updated.Status.Conditions[i].Type == v1.PodScheduled {
+            if updated.Status.Conditions[i].Status == condition.Status {
+                condition.LastTransitionTime = updated.Status.Conditions[i].LastTransitionTime
+            }
+            updated.Status.Conditions[i] = condition
+            replaced = true
+            break
+        }
+    }
+    if !replaced {
+        updated.Status.Conditions = append(updated.Status.Conditions, condition)
+    }
+
+    _, err := ctl.clientset.CoreV1().Pods(p.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+    return err
+}
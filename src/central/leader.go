@@ -0,0 +1,70 @@
+package central
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+/*
+Runs the central scheduler. With leader election enabled every replica keeps
+its node cache and placement server running so standbys stay warm, but only
+the replica holding the Lease watches the queue and binds pods
+*/
+func (ctl *CentralScheduler) Run() {
+    if !ctl.config.LeaderElect {
+        ctl.Schedule()
+        return
+    }
+
+    identity, err := os.Hostname()
+    if err != nil {
+        log.WithFields(log.Fields{
+            "ERROR": err,
+        }).Fatal("HOSTNAME ERROR")
+    }
+
+    lock := &resourcelock.LeaseLock{
+        LeaseMeta: metav1.ObjectMeta{
+            Name:      ctl.config.LeaseName,
+            Namespace: ctl.config.LeaseNamespace,
+        },
+        Client: ctl.clientset.CoordinationV1(),
+        LockConfig: resourcelock.ResourceLockConfig{
+            Identity: identity,
+        },
+    }
+
+    leaderelection.RunOrDie(context.Background(), leaderelection.LeaderElectionConfig{
+        Lock:            lock,
+        ReleaseOnCancel: true,
+        LeaseDuration:   15 * time.Second,
+        RenewDeadline:   10 * time.Second,
+        RetryPeriod:     2 * time.Second,
+        Callbacks: leaderelection.LeaderCallbacks{
+            OnStartedLeading: func(ctx context.Context) {
+                log.WithFields(log.Fields{
+                    "IDENTITY": identity,
+                }).Debug("STARTED LEADING")
+                ctl.Schedule()
+            },
+            OnStoppedLeading: func() {
+                /* Exit so that no bind from this replica races the new leader */
+                log.WithFields(log.Fields{
+                    "IDENTITY": identity,
+                }).Fatal("LOST LEADERSHIP")
+            },
+            OnNewLeader: func(leader string) {
+                log.WithFields(log.Fields{
+                    "LEADER": leader,
+                }).Debug("NEW LEADER ELECTED")
+            },
+        },
+    })
+}
@@ -0,0 +1,276 @@
+package central
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+    controlPlaneLabel = "node-role.kubernetes.io/control-plane"
+    nodeNameIndex     = "nodeName"
+)
+
+/*
+Starts a shared informer over the cluster's nodes. The signal table grows and
+shrinks as nodes join, leave, are cordoned or stop reporting Ready, so
+findNode only ever considers nodes that can currently accept pods
+*/
+func (ctl *CentralScheduler) startInformers() {
+    ctl.informerFactory = informers.NewSharedInformerFactory(ctl.clientset, 0)
+
+    nodeInformer := ctl.informerFactory.Core().V1().Nodes()
+    nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+        AddFunc:    ctl.onNodeAdd,
+        UpdateFunc: ctl.onNodeUpdate,
+        DeleteFunc: ctl.onNodeDelete,
+    })
+    ctl.nodeLister = nodeInformer.Lister()
+
+    /* Pods are indexed by node so predicates can see what is already placed */
+    podInformer := ctl.informerFactory.Core().V1().Pods()
+    podInformer.Informer().AddIndexers(cache.Indexers{
+        nodeNameIndex: indexByNodeName,
+    })
+    ctl.podIndexer = podInformer.Informer().GetIndexer()
+
+    /* Unassigned pods for this scheduler feed the scheduling queue */
+    podInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+        FilterFunc: ctl.responsibleForPod,
+        Handler: cache.ResourceEventHandlerFuncs{
+            AddFunc:    ctl.onPodAdd,
+            UpdateFunc: ctl.onPodUpdate,
+            DeleteFunc: ctl.onPodDelete,
+        },
+    })
+
+    /* Once the informer sees a pod bound, it no longer needs to be assumed */
+    podInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+        FilterFunc: isAssigned,
+        Handler: cache.ResourceEventHandlerFuncs{
+            AddFunc:    ctl.onAssignedPod,
+            UpdateFunc: func(_, newObj interface{}) { ctl.onAssignedPod(newObj) },
+            DeleteFunc: ctl.onAssignedPod,
+        },
+    })
+
+    ctl.informerFactory.Start(ctl.stopCh)
+
+    for informer, synced := range ctl.informerFactory.WaitForCacheSync(ctl.stopCh) {
+        if !synced {
+            log.WithFields(log.Fields{
+                "INFORMER": informer,
+            }).Fatal("FAILED TO SYNC INFORMER CACHE")
+        }
+    }
+
+    log.WithFields(log.Fields{
+        "NODES": len(ctl.nodeSignals),
+    }).Debug("SYNCED NODE CACHE")
+}
+
+func indexByNodeName(obj interface{}) ([]string, error) {
+    p, ok := obj.(*v1.Pod)
+    if !ok || p.Spec.NodeName == "" {
+        return []string{}, nil
+    }
+    return []string{p.Spec.NodeName}, nil
+}
+
+/* Returns the names of all nodes currently in the signal table */
+func (ctl *CentralScheduler) nodeNames() []string {
+    ctl.mu.RLock()
+    defer ctl.mu.RUnlock()
+
+    names := make([]string, 0, len(ctl.nodeSignals))
+    for name := range ctl.nodeSignals {
+        names = append(names, name)
+    }
+    return names
+}
+
+/* Builds a NodeInfo for Node n from the informer caches */
+func (ctl *CentralScheduler) nodeInfo(n string) (*NodeInfo, error) {
+    node, err := ctl.nodeLister.Get(n)
+    if err != nil {
+        return nil, err
+    }
+
+    objs, err := ctl.podIndexer.ByIndex(nodeNameIndex, n)
+    if err != nil {
+        return nil, err
+    }
+
+    info := &NodeInfo{
+        Node: node,
+        Pods: make([]*v1.Pod, 0, len(objs)),
+    }
+    seen := make(map[string]bool, len(objs))
+    for _, obj := range objs {
+        p, ok := obj.(*v1.Pod)
+        if !ok {
+            continue
+        }
+        /* Terminated pods no longer hold resources or ports */
+        if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+            continue
+        }
+        info.Pods = append(info.Pods, p)
+        seen[p.Namespace+"/"+p.Name] = true
+    }
+
+    /* Pods whose bind is in flight count against the node they were assumed on */
+    for _, p := range ctl.assumed.PodsOn(n) {
+        if !seen[p.Namespace+"/"+p.Name] {
+            info.Pods = append(info.Pods, p)
+        }
+    }
+    return info, nil
+}
+
+/* Returns whether pods may currently be placed on Node n */
+func isSchedulable(n *v1.Node) bool {
+    if _, ok := n.Labels[controlPlaneLabel]; ok {
+        return false
+    }
+    if n.Spec.Unschedulable {
+        return false
+    }
+    for _, cond := range n.Status.Conditions {
+        if cond.Type == v1.NodeReady {
+            return cond.Status == v1.ConditionTrue
+        }
+    }
+    return false
+}
+
+/* Adds Node n to the signal table, starting with no capacity */
+func (ctl *CentralScheduler) addNode(n string) {
+    ctl.mu.Lock()
+    defer ctl.mu.Unlock()
+
+    if _, ok := ctl.nodeSignals[n]; ok {
+        return
+    }
+
+    ctl.nodeSignals[n] = newNodeSignal()
+
+    log.WithFields(log.Fields{
+        "NODE": n,
+    }).Debug("ADDED NODE")
+
+    ctl.queue.MoveAllToActiveOrBackoff()
+}
+
+/* Removes Node n from the signal table */
+func (ctl *CentralScheduler) removeNode(n string) {
+    ctl.mu.Lock()
+    defer ctl.mu.Unlock()
+
+    if _, ok := ctl.nodeSignals[n]; !ok {
+        return
+    }
+    delete(ctl.nodeSignals, n)
+
+    log.WithFields(log.Fields{
+        "NODE": n,
+    }).Debug("REMOVED NODE")
+}
+
+func (ctl *CentralScheduler) onNodeAdd(obj interface{}) {
+    n, ok := obj.(*v1.Node)
+    if !ok {
+        return
+    }
+    if isSchedulable(n) {
+        ctl.addNode(n.Name)
+    }
+}
+
+func (ctl *CentralScheduler) onNodeUpdate(_, newObj interface{}) {
+    n, ok := newObj.(*v1.Node)
+    if !ok {
+        return
+    }
+    if isSchedulable(n) {
+        ctl.addNode(n.Name)
+    } else {
+        ctl.removeNode(n.Name)
+    }
+}
+
+func (ctl *CentralScheduler) onNodeDelete(obj interface{}) {
+    switch t := obj.(type) {
+    case *v1.Node:
+        ctl.removeNode(t.Name)
+    case cache.DeletedFinalStateUnknown:
+        if n, ok := t.Obj.(*v1.Node); ok {
+            ctl.removeNode(n.Name)
+        }
+    }
+}
+
+/* Returns whether obj is a pending pod that this scheduler should place */
+func (ctl *CentralScheduler) responsibleForPod(obj interface{}) bool {
+    var p *v1.Pod
+    switch t := obj.(type) {
+    case *v1.Pod:
+        p = t
+    case cache.DeletedFinalStateUnknown:
+        var ok bool
+        if p, ok = t.Obj.(*v1.Pod); !ok {
+            return false
+        }
+    default:
+        return false
+    }
+    _, ok := ctl.profiles[p.Spec.SchedulerName]
+    return ok && p.Spec.NodeName == ""
+}
+
+func (ctl *CentralScheduler) onPodAdd(obj interface{}) {
+    if p, ok := obj.(*v1.Pod); ok {
+        ctl.queue.Add(p)
+    }
+}
+
+func (ctl *CentralScheduler) onPodUpdate(_, newObj interface{}) {
+    if p, ok := newObj.(*v1.Pod); ok {
+        ctl.queue.Update(p)
+    }
+}
+
+func (ctl *CentralScheduler) onPodDelete(obj interface{}) {
+    switch t := obj.(type) {
+    case *v1.Pod:
+        ctl.queue.Delete(t)
+    case cache.DeletedFinalStateUnknown:
+        if p, ok := t.Obj.(*v1.Pod); ok {
+            ctl.queue.Delete(p)
+        }
+    }
+}
+
+func isAssigned(obj interface{}) bool {
+    switch t := obj.(type) {
+    case *v1.Pod:
+        return t.Spec.NodeName != ""
+    case cache.DeletedFinalStateUnknown:
+        p, ok := t.Obj.(*v1.Pod)
+        return ok && p.Spec.NodeName != ""
+    }
+    return false
+}
+
+func (ctl *CentralScheduler) onAssignedPod(obj interface{}) {
+    switch t := obj.(type) {
+    case *v1.Pod:
+        ctl.assumed.Forget(t)
+    case cache.DeletedFinalStateUnknown:
+        if p, ok := t.Obj.(*v1.Pod); ok {
+            ctl.assumed.Forget(p)
+        }
+    }
+}
@@ -0,0 +1,138 @@
+package central
+
+import (
+	"fmt"
+	"math"
+
+	log "github.com/sirupsen/logrus"
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/LucaChot/pronto/src/schema"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+/* How the central scheduler estimates the load a newly placed pod adds */
+type PenaltyMode string
+
+const (
+    /* Signals are left untouched until the node reports again */
+    PenaltyNone      PenaltyMode = "none"
+    /* Every placement adds PenaltyIncrement */
+    PenaltyFixed     PenaltyMode = "fixed"
+    /*
+    The pod's requests, as a fraction of the node's allocatable, are projected
+    through the node's last U·Sigma in the same way remote schedulers compute
+    their job signal. Falls back to PenaltyIncrement without a U·Sigma
+    */
+    PenaltyProjected PenaltyMode = "projected"
+)
+
+func ParsePenaltyMode(s string) (PenaltyMode, error) {
+    switch PenaltyMode(s) {
+    case PenaltyNone, PenaltyFixed, PenaltyProjected:
+        return PenaltyMode(s), nil
+    }
+    return "", fmt.Errorf("unknown penalty mode %q", s)
+}
+
+/* Returns the penalty to apply to Node n for placing Pod p */
+func (ctl *CentralScheduler) placementPenalty(p *v1.Pod, n string, s *Signal) float64 {
+    switch ctl.config.PenaltyMode {
+    case PenaltyFixed:
+        return ctl.config.PenaltyIncrement
+    case PenaltyProjected:
+        if s.USigma == nil {
+            return ctl.config.PenaltyIncrement
+        }
+        node, err := ctl.nodeLister.Get(n)
+        if err != nil {
+            return ctl.config.PenaltyIncrement
+        }
+        return projectedPenalty(podRequestVector(p, node, ctl.config.Schema), s.USigma)
+    }
+    return 0
+}
+
+/*
+Requests of Pod p as fractions of Node n's allocatable, laid out in the rows
+of metric schema s. Dimensions that requests say nothing about, such as disk,
+are left at 0
+*/
+func podRequestVector(p *v1.Pod, n *v1.Node, s schema.Schema) *mat.VecDense {
+    reqs := podRequests(p)
+    alloc := n.Status.Allocatable
+
+    fraction := func(name v1.ResourceName) float64 {
+        a, ok := alloc[name]
+        if !ok || a.IsZero() {
+            return 0
+        }
+        r := reqs[name]
+        return float64(r.MilliValue()) / float64(a.MilliValue())
+    }
+
+    y := mat.NewVecDense(s.D(), nil)
+    if i := s.Index(schema.CPU); i >= 0 {
+        y.SetVec(i, fraction(v1.ResourceCPU))
+    }
+    if i := s.Index(schema.Memory); i >= 0 {
+        y.SetVec(i, fraction(v1.ResourceMemory))
+    }
+    return y
+}
+
+/* Sum of |yᵀU|·Sigma, with U·Sigma given as a single product */
+func projectedPenalty(y *mat.VecDense, uSigma *mat.Dense) float64 {
+    d, _ := uSigma.Dims()
+    if y.Len() != d {
+        return 0
+    }
+
+    var temp mat.Dense
+    temp.Mul(y.T(), uSigma)
+
+    var total float64
+    for _, v := range temp.RawMatrix().Data {
+        total += math.Abs(v)
+    }
+    return total
+}
+
+/* Applies the placement penalty for Pod p to Node n's signal */
+func (ctl *CentralScheduler) applyPenalty(p *v1.Pod, n string) float64 {
+    ctl.mu.RLock()
+    ns, ok := ctl.nodeSignals[n]
+    ctl.mu.RUnlock()
+    if !ok {
+        return 0
+    }
+
+    penalty := ctl.placementPenalty(p, n, ns.Load())
+    if penalty == 0 {
+        return 0
+    }
+    s := ns.AddPenalty(penalty)
+
+    log.WithFields(log.Fields{
+        "NODE":    n,
+        "PENALTY": penalty,
+        "TOTAL":   s.Penalty,
+    }).Debug("APPLIED PLACEMENT PENALTY")
+    return penalty
+}
+
+/* Reverts a penalty after the bind it was applied for failed */
+func (ctl *CentralScheduler) revertPenalty(n string, penalty float64) {
+    if penalty == 0 {
+        return
+    }
+
+    ctl.mu.RLock()
+    ns, ok := ctl.nodeSignals[n]
+    ctl.mu.RUnlock()
+    if !ok {
+        return
+    }
+    ns.AddPenalty(-penalty)
+}
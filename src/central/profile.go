@@ -0,0 +1,126 @@
+package central
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+/*
+A scheduler profile, matched against a pod's spec.schedulerName. Every
+profile shares the central scheduler's node signal table
+*/
+type Profile struct {
+    SchedulerName string `json:"schedulerName"`
+
+    /* Strategy used to pick among feasible nodes, see NewScorer */
+    Scorer             string  `json:"scorer"`
+    SoftmaxTemperature float64 `json:"softmaxTemperature"`
+    FitWeight          float64 `json:"fitWeight"`
+
+    /* Only nodes whose signal is below the threshold are candidates */
+    Threshold float64 `json:"threshold"`
+
+    /* Names of the predicates to run, all of DefaultPredicates when empty */
+    Filters []string `json:"filters"`
+}
+
+func DefaultProfile() Profile {
+    return Profile{
+        SchedulerName:      "pronto",
+        Scorer:             ScorerArgmin,
+        SoftmaxTemperature: 0.1,
+        FitWeight:          0.5,
+        Threshold:          unknownSignal,
+    }
+}
+
+/*
+Reads the profiles from a YAML or JSON file. Fields a profile leaves out take
+the values of DefaultProfile
+*/
+func LoadProfiles(path string) ([]Profile, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var raw struct {
+        Profiles []map[string]interface{} `json:"profiles"`
+    }
+    if err := yaml.Unmarshal(data, &raw); err != nil {
+        return nil, fmt.Errorf("parsing %s: %w", path, err)
+    }
+    if len(raw.Profiles) == 0 {
+        return nil, fmt.Errorf("%s defines no profiles", path)
+    }
+
+    /* Decode each profile on top of the defaults */
+    profiles := make([]Profile, 0, len(raw.Profiles))
+    for _, fields := range raw.Profiles {
+        encoded, err := yaml.Marshal(fields)
+        if err != nil {
+            return nil, err
+        }
+        profile := DefaultProfile()
+        if err := yaml.UnmarshalStrict(encoded, &profile); err != nil {
+            return nil, fmt.Errorf("parsing %s: %w", path, err)
+        }
+        profiles = append(profiles, profile)
+    }
+    return profiles, nil
+}
+
+/* A profile with its scorer and predicates built */
+type schedProfile struct {
+    name       string
+    scorer     Scorer
+    predicates []Predicate
+    threshold  float64
+}
+
+func compileProfile(profile Profile) (*schedProfile, error) {
+    if profile.SchedulerName == "" {
+        return nil, fmt.Errorf("profile has no schedulerName")
+    }
+    if profile.Threshold <= 0 || profile.Threshold > unknownSignal {
+        return nil, fmt.Errorf("profile %s: threshold must be in (0, 1]", profile.SchedulerName)
+    }
+
+    scorer, err := NewScorer(profile)
+    if err != nil {
+        return nil, fmt.Errorf("profile %s: %w", profile.SchedulerName, err)
+    }
+
+    predicates, err := PredicatesByName(profile.Filters)
+    if err != nil {
+        return nil, fmt.Errorf("profile %s: %w", profile.SchedulerName, err)
+    }
+
+    return &schedProfile{
+        name:       profile.SchedulerName,
+        scorer:     scorer,
+        predicates: predicates,
+        threshold:  profile.Threshold,
+    }, nil
+}
+
+func compileProfiles(profiles []Profile) (map[string]*schedProfile, error) {
+    if len(profiles) == 0 {
+        return nil, fmt.Errorf("no scheduler profiles configured")
+    }
+
+    compiled := make(map[string]*schedProfile, len(profiles))
+    for _, profile := range profiles {
+        sp, err := compileProfile(profile)
+        if err != nil {
+            return nil, err
+        }
+        if _, ok := compiled[sp.name]; ok {
+            return nil, fmt.Errorf("duplicate profile %s", sp.name)
+        }
+        compiled[sp.name] = sp
+    }
+    return compiled, nil
+}
@@ -0,0 +1,297 @@
+package central
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+    InitialBackoff            = time.Second
+    MaxBackoff                = 10 * time.Second
+    MaxUnschedulableDuration  = 60 * time.Second
+    queueFlushPeriod          = time.Second
+)
+
+/* A pod waiting to be scheduled along with its retry state */
+type QueuedPod struct {
+    Pod       *v1.Pod
+    Attempts  int
+    Timestamp time.Time
+}
+
+func (qp *QueuedPod) key() string {
+    key, _ := cache.MetaNamespaceKeyFunc(qp.Pod)
+    return key
+}
+
+/*
+Scheduling queue modelled on kube-scheduler's priority queue:
+- active holds pods ready to be scheduled, in FIFO order
+- backoff holds pods that failed and are waiting out their backoff
+- unschedulable holds pods that failed and are waiting for the cluster to change
+
+A pod is in at most one of the three, or in flight between Pop and Done
+*/
+type SchedulingQueue struct {
+    mu   sync.Mutex
+    cond *sync.Cond
+
+    active        []*QueuedPod
+    backoff       map[string]*QueuedPod
+    unschedulable map[string]*QueuedPod
+    inFlight      map[string]bool
+
+    initialBackoff time.Duration
+    maxBackoff     time.Duration
+
+    closed bool
+}
+
+func NewSchedulingQueue() *SchedulingQueue {
+    q := &SchedulingQueue{
+        backoff:        make(map[string]*QueuedPod),
+        unschedulable:  make(map[string]*QueuedPod),
+        inFlight:       make(map[string]bool),
+        initialBackoff: InitialBackoff,
+        maxBackoff:     MaxBackoff,
+    }
+    q.cond = sync.NewCond(&q.mu)
+    return q
+}
+
+/* Periodically moves pods whose backoff or unschedulable timeout has expired */
+func (q *SchedulingQueue) Run(stopCh <-chan struct{}) {
+    ticker := time.NewTicker(queueFlushPeriod)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-stopCh:
+            q.Close()
+            return
+        case <-ticker.C:
+            q.flushBackoff()
+            q.flushUnschedulable()
+        }
+    }
+}
+
+/* Adds a newly created pod to the active queue */
+func (q *SchedulingQueue) Add(p *v1.Pod) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    qp := &QueuedPod{
+        Pod:       p,
+        Timestamp: time.Now(),
+    }
+    if q.contains(qp.key()) {
+        return
+    }
+    q.pushActive(qp)
+}
+
+/* Replaces the stored object of a queued pod, if present */
+func (q *SchedulingQueue) Update(p *v1.Pod) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    key, _ := cache.MetaNamespaceKeyFunc(p)
+    for _, qp := range q.active {
+        if qp.key() == key {
+            qp.Pod = p
+            return
+        }
+    }
+    if qp, ok := q.backoff[key]; ok {
+        qp.Pod = p
+    } else if qp, ok := q.unschedulable[key]; ok {
+        qp.Pod = p
+    }
+}
+
+/* Removes a pod that was deleted or bound elsewhere */
+func (q *SchedulingQueue) Delete(p *v1.Pod) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    key, _ := cache.MetaNamespaceKeyFunc(p)
+    for i, qp := range q.active {
+        if qp.key() == key {
+            q.active = append(q.active[:i], q.active[i+1:]...)
+            break
+        }
+    }
+    delete(q.backoff, key)
+    delete(q.unschedulable, key)
+    delete(q.inFlight, key)
+}
+
+/* Blocks until a pod is active, returns false once the queue is closed */
+func (q *SchedulingQueue) Pop() (*QueuedPod, bool) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    for len(q.active) == 0 && !q.closed {
+        q.cond.Wait()
+    }
+    if q.closed {
+        return nil, false
+    }
+
+    qp := q.active[0]
+    q.active = q.active[1:]
+    qp.Attempts++
+    q.inFlight[qp.key()] = true
+    return qp, true
+}
+
+/* Marks a popped pod as finished, e.g. after it was bound */
+func (q *SchedulingQueue) Done(qp *QueuedPod) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    delete(q.inFlight, qp.key())
+}
+
+/* Returns a popped pod that could not be placed to the unschedulable queue */
+func (q *SchedulingQueue) AddUnschedulable(qp *QueuedPod) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    key := qp.key()
+    if !q.inFlight[key] {
+        /* Deleted while it was being scheduled */
+        return
+    }
+    delete(q.inFlight, key)
+
+    qp.Timestamp = time.Now()
+    q.unschedulable[key] = qp
+}
+
+/* Returns a popped pod that hit a transient error to the backoff queue */
+func (q *SchedulingQueue) AddBackoff(qp *QueuedPod) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    key := qp.key()
+    if !q.inFlight[key] {
+        return
+    }
+    delete(q.inFlight, key)
+
+    qp.Timestamp = time.Now()
+    q.backoff[key] = qp
+}
+
+/*
+Called when the cluster changes in a way that may make unschedulable pods
+feasible, e.g. a node reports a new signal or joins the cluster
+*/
+func (q *SchedulingQueue) MoveAllToActiveOrBackoff() {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    if len(q.unschedulable) == 0 {
+        return
+    }
+
+    now := time.Now()
+    for key, qp := range q.unschedulable {
+        delete(q.unschedulable, key)
+        if now.Before(q.backoffExpiry(qp)) {
+            q.backoff[key] = qp
+        } else {
+            q.pushActive(qp)
+        }
+    }
+}
+
+/* Number of pods in the active, backoff and unschedulable queues */
+func (q *SchedulingQueue) Len() (int, int, int) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    return len(q.active), len(q.backoff), len(q.unschedulable)
+}
+
+func (q *SchedulingQueue) Close() {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    q.closed = true
+    q.cond.Broadcast()
+}
+
+func (q *SchedulingQueue) flushBackoff() {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    for key, qp := range q.backoff {
+        if now.Before(q.backoffExpiry(qp)) {
+            continue
+        }
+        delete(q.backoff, key)
+        q.pushActive(qp)
+    }
+}
+
+func (q *SchedulingQueue) flushUnschedulable() {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    for key, qp := range q.unschedulable {
+        if now.Sub(qp.Timestamp) < MaxUnschedulableDuration {
+            continue
+        }
+        delete(q.unschedulable, key)
+        q.pushActive(qp)
+    }
+}
+
+/* Backoff doubles with every failed attempt up to maxBackoff */
+func (q *SchedulingQueue) backoffExpiry(qp *QueuedPod) time.Time {
+    backoff := q.initialBackoff
+    for i := 1; i < qp.Attempts; i++ {
+        backoff *= 2
+        if backoff >= q.maxBackoff {
+            backoff = q.maxBackoff
+            break
+        }
+    }
+    return qp.Timestamp.Add(backoff)
+}
+
+/* Must be called with mu held */
+func (q *SchedulingQueue) contains(key string) bool {
+    if q.inFlight[key] {
+        return true
+    }
+    if _, ok := q.backoff[key]; ok {
+        return true
+    }
+    if _, ok := q.unschedulable[key]; ok {
+        return true
+    }
+    for _, qp := range q.active {
+        if qp.key() == key {
+            return true
+        }
+    }
+    return false
+}
+
+/* Must be called with mu held */
+func (q *SchedulingQueue) pushActive(qp *QueuedPod) {
+    q.active = append(q.active, qp)
+    q.cond.Signal()
+
+    log.WithFields(log.Fields{
+        "POD":      qp.key(),
+        "ATTEMPTS": qp.Attempts,
+    }).Debug("QUEUED POD")
+}
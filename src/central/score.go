@@ -0,0 +1,208 @@
+package central
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+/* A feasible node together with its current job signal */
+type Candidate struct {
+    Info   *NodeInfo
+    Signal float64
+}
+
+func (c Candidate) Name() string {
+    return c.Info.Node.Name
+}
+
+/*
+A Scorer picks the node for a pod among the feasible candidates. Every
+candidate has a signal below the unknown value of 1
+*/
+type Scorer interface {
+    Name() string
+    Pick(p *v1.Pod, candidates []Candidate) Candidate
+}
+
+const (
+    ScorerArgmin         = "argmin"
+    ScorerPowerOfTwo     = "power-of-two"
+    ScorerWeightedRandom = "weighted-random"
+    ScorerSoftmax        = "softmax"
+    ScorerFitBlend       = "fit-blend"
+)
+
+/* Builds the scorer named in the profile */
+func NewScorer(config Profile) (Scorer, error) {
+    switch config.Scorer {
+    case ScorerArgmin:
+        return ArgminScorer{}, nil
+    case ScorerPowerOfTwo:
+        return PowerOfTwoScorer{}, nil
+    case ScorerWeightedRandom:
+        return WeightedRandomScorer{}, nil
+    case ScorerSoftmax:
+        if config.SoftmaxTemperature <= 0 {
+            return nil, fmt.Errorf("softmax temperature must be positive")
+        }
+        return SoftmaxScorer{Temperature: config.SoftmaxTemperature}, nil
+    case ScorerFitBlend:
+        if config.FitWeight < 0 || config.FitWeight > 1 {
+            return nil, fmt.Errorf("fit weight must be between 0 and 1")
+        }
+        return FitBlendScorer{FitWeight: config.FitWeight}, nil
+    }
+    return nil, fmt.Errorf("unknown scorer %q", config.Scorer)
+}
+
+/* Lowest signal wins, ties are broken uniformly at random */
+type ArgminScorer struct{}
+
+func (ArgminScorer) Name() string { return ScorerArgmin }
+
+func (ArgminScorer) Pick(_ *v1.Pod, candidates []Candidate) Candidate {
+    return argmin(candidates, func(c Candidate) float64 { return c.Signal })
+}
+
+/* Samples two candidates and keeps the one with the lower signal */
+type PowerOfTwoScorer struct{}
+
+func (PowerOfTwoScorer) Name() string { return ScorerPowerOfTwo }
+
+func (PowerOfTwoScorer) Pick(_ *v1.Pod, candidates []Candidate) Candidate {
+    if len(candidates) == 1 {
+        return candidates[0]
+    }
+    i := rand.IntN(len(candidates))
+    j := rand.IntN(len(candidates) - 1)
+    if j >= i {
+        j++
+    }
+    if candidates[j].Signal < candidates[i].Signal {
+        return candidates[j]
+    }
+    return candidates[i]
+}
+
+/* Samples a candidate with probability proportional to 1 - signal */
+type WeightedRandomScorer struct{}
+
+func (WeightedRandomScorer) Name() string { return ScorerWeightedRandom }
+
+func (WeightedRandomScorer) Pick(_ *v1.Pod, candidates []Candidate) Candidate {
+    weights := make([]float64, len(candidates))
+    for i, c := range candidates {
+        weights[i] = max(1-c.Signal, 0)
+    }
+    return sample(candidates, weights)
+}
+
+/*
+Samples a candidate with probability proportional to exp(-signal / T). Low
+temperatures approach argmin, high temperatures approach uniform
+*/
+type SoftmaxScorer struct {
+    Temperature float64
+}
+
+func (SoftmaxScorer) Name() string { return ScorerSoftmax }
+
+func (s SoftmaxScorer) Pick(_ *v1.Pod, candidates []Candidate) Candidate {
+    /* Shift by the minimum so the largest exponent is 0 */
+    minSignal := math.Inf(1)
+    for _, c := range candidates {
+        minSignal = min(minSignal, c.Signal)
+    }
+
+    weights := make([]float64, len(candidates))
+    for i, c := range candidates {
+        weights[i] = math.Exp(-(c.Signal - minSignal) / s.Temperature)
+    }
+    return sample(candidates, weights)
+}
+
+/*
+Minimises a blend of the job signal and the fraction of the node's CPU and
+memory that would be requested once the pod is placed
+*/
+type FitBlendScorer struct {
+    /* 0 scores on the signal alone, 1 on the requested-resource fit alone */
+    FitWeight float64
+}
+
+func (FitBlendScorer) Name() string { return ScorerFitBlend }
+
+func (s FitBlendScorer) Pick(p *v1.Pod, candidates []Candidate) Candidate {
+    requested := podRequests(p)
+    return argmin(candidates, func(c Candidate) float64 {
+        fit := requestedFraction(requested, c.Info)
+        return (1-s.FitWeight)*c.Signal + s.FitWeight*fit
+    })
+}
+
+/*
+Largest fraction of allocatable CPU or memory that would be requested on the
+node once a pod with the given requests is placed on it
+*/
+func requestedFraction(requested v1.ResourceList, n *NodeInfo) float64 {
+    used := v1.ResourceList{}
+    for _, existing := range n.Pods {
+        addResourceList(used, podRequests(existing))
+    }
+    addResourceList(used, requested)
+
+    var fraction float64
+    for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+        alloc, ok := n.Node.Status.Allocatable[name]
+        if !ok || alloc.IsZero() {
+            continue
+        }
+        u := used[name]
+        fraction = max(fraction, float64(u.MilliValue())/float64(alloc.MilliValue()))
+    }
+    return fraction
+}
+
+/* Candidate with the lowest score, ties are broken uniformly at random */
+func argmin(candidates []Candidate, score func(Candidate) float64) Candidate {
+    best := candidates[0]
+    bestScore := score(best)
+    ties := 1
+    for _, c := range candidates[1:] {
+        s := score(c)
+        switch {
+        case s < bestScore:
+            best, bestScore, ties = c, s, 1
+        case s == bestScore:
+            /* Reservoir sampling keeps each tied candidate with equal chance */
+            ties++
+            if rand.IntN(ties) == 0 {
+                best = c
+            }
+        }
+    }
+    return best
+}
+
+/* Samples a candidate with probability proportional to its weight */
+func sample(candidates []Candidate, weights []float64) Candidate {
+    var total float64
+    for _, w := range weights {
+        total += w
+    }
+    if total <= 0 {
+        return candidates[rand.IntN(len(candidates))]
+    }
+
+    target := rand.Float64() * total
+    for i, w := range weights {
+        target -= w
+        if target < 0 {
+            return candidates[i]
+        }
+    }
+    return candidates[len(candidates)-1]
+}
@@ -2,12 +2,15 @@ package central
 
 import (
 	"context"
-	"math"
+	"errors"
 	"net"
+	"time"
 
 	pb "github.com/LucaChot/pronto/src/message"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 
@@ -40,11 +43,49 @@ func (ctl *CentralScheduler) RequestPod(ctx context.Context, in *pb.PodRequest)
     log.WithFields(log.Fields{
         "SIGNAL":      in.Signal,
         "NODE":     in.Node,
+        "SEQUENCE": in.Sequence,
     }).Debug("RECEIVED JOB SIGNAL")
 
-    index := ctl.nodeMap[in.Node]
-    ctl.nodeSignals[index].Store(math.Float64bits(in.Signal))
+    err := ctl.recordSignal(in.Node, &Signal{
+        Value:    in.Signal,
+        Received: time.Now(),
+        Sequence: in.Sequence,
+    })
+    if err == errUnknownNode {
+        return nil, status.Errorf(codes.NotFound, "unknown node %q", in.Node)
+    }
 
     return &pb.EmptyReply{}, nil
 }
 
+var (
+    errUnknownNode = errors.New("unknown node")
+    errOutOfOrder  = errors.New("out of order sequence")
+)
+
+/* Stores a signal received from Node n over either RPC */
+func (ctl *CentralScheduler) recordSignal(n string, s *Signal) error {
+    ctl.mu.RLock()
+    nodeSignal, ok := ctl.nodeSignals[n]
+    ctl.mu.RUnlock()
+
+    if !ok {
+        log.WithFields(log.Fields{
+            "NODE":     n,
+        }).Debug("IGNORED SIGNAL FROM UNKNOWN NODE")
+        return errUnknownNode
+    }
+
+    if !nodeSignal.Update(s, ctl.config.SignalTTL) {
+        log.WithFields(log.Fields{
+            "NODE":     n,
+            "SEQUENCE": s.Sequence,
+        }).Debug("IGNORED OUT OF ORDER SIGNAL")
+        return errOutOfOrder
+    }
+
+    /* A new signal may make previously unschedulable pods feasible */
+    ctl.queue.MoveAllToActiveOrBackoff()
+
+    return nil
+}
@@ -0,0 +1,123 @@
+package central
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/* How signals older than the TTL are treated */
+type StalePolicy string
+
+const (
+    /* Stale nodes are treated as unknown and excluded from findNode */
+    StaleExpire StalePolicy = "expire"
+    /* Stale signals decay linearly back to unknown over a further TTL */
+    StaleDecay  StalePolicy = "decay"
+)
+
+func ParseStalePolicy(s string) (StalePolicy, error) {
+    switch StalePolicy(s) {
+    case StaleExpire, StaleDecay:
+        return StalePolicy(s), nil
+    }
+    return "", fmt.Errorf("unknown stale policy %q", s)
+}
+
+/* Signal value reported by a node that has no usable data */
+const unknownSignal = 1.0
+
+/* A job signal as received from a remote scheduler */
+type Signal struct {
+    Value    float64
+    Received time.Time
+    /* 0 when the remote scheduler did not send one */
+    Sequence uint64
+    /*
+    Load added by placements since this signal was received. A new signal
+    from the node replaces it, clearing the penalty
+    */
+    Penalty  float64
+    /* Node's last U·Sigma, nil if it has not reported one */
+    USigma   *mat.Dense
+}
+
+/* Latest signal of a node, replaced atomically on every report */
+type nodeSignal struct {
+    latest atomic.Pointer[Signal]
+}
+
+func newNodeSignal() *nodeSignal {
+    ns := &nodeSignal{}
+    ns.latest.Store(&Signal{Value: unknownSignal})
+    return ns
+}
+
+func (ns *nodeSignal) Load() *Signal {
+    return ns.latest.Load()
+}
+
+/*
+Stores a new signal unless it is older than the one held. A sequence number
+that goes backwards is only accepted once the held signal has gone stale, which
+covers a remote scheduler that restarted and began counting from 1 again.
+Without a TTL nothing goes stale, so sequence numbers never go backwards
+*/
+func (ns *nodeSignal) Update(s *Signal, ttl time.Duration) bool {
+    for {
+        cur := ns.latest.Load()
+        stale := ttl > 0 && s.Received.Sub(cur.Received) >= ttl
+        if s.Sequence != 0 && cur.Sequence != 0 && s.Sequence <= cur.Sequence && !stale {
+            return false
+        }
+        /* Unary reports carry no U·Sigma, keep the last one we saw */
+        if s.USigma == nil {
+            s.USigma = cur.USigma
+        }
+        if ns.latest.CompareAndSwap(cur, s) {
+            return true
+        }
+    }
+}
+
+/*
+Returns the value findNode should use for Signal s at time now, and whether
+the signal is still fresh
+*/
+func effectiveSignal(s *Signal, now time.Time, ttl time.Duration, policy StalePolicy) (float64, bool) {
+    if s.Received.IsZero() {
+        return unknownSignal, false
+    }
+
+    value := s.Value + s.Penalty
+
+    age := now.Sub(s.Received)
+    if ttl <= 0 || age <= ttl {
+        return value, true
+    }
+
+    if policy == StaleDecay {
+        frac := float64(age-ttl) / float64(ttl)
+        if frac < 1 {
+            return value + (unknownSignal-value)*frac, false
+        }
+    }
+    return unknownSignal, false
+}
+
+/*
+Adds a placement penalty on top of the held signal so that the next decisions
+see the node as busier until it reports again. Returns the new signal
+*/
+func (ns *nodeSignal) AddPenalty(penalty float64) *Signal {
+    for {
+        cur := ns.latest.Load()
+        next := *cur
+        next.Penalty = max(next.Penalty+penalty, 0)
+        if ns.latest.CompareAndSwap(cur, &next) {
+            return &next
+        }
+    }
+}
@@ -0,0 +1,209 @@
+package central
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gonum.org/v1/gonum/mat"
+
+	pb "github.com/LucaChot/pronto/src/message"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+    /* Messages queued for a slow remote scheduler before new ones are dropped */
+    streamBuffer = 32
+)
+
+/* Outgoing half of a remote scheduler's StreamSignals connection */
+type signalStream struct {
+    node string
+    out  chan *pb.CentralMessage
+}
+
+/* Queues msg for the remote scheduler, dropping it if the stream is backed up */
+func (ss *signalStream) push(msg *pb.CentralMessage) bool {
+    select {
+    case ss.out <- msg:
+        return true
+    default:
+        log.WithFields(log.Fields{
+            "NODE": ss.node,
+        }).Debug("DROPPED STREAM MESSAGE")
+        return false
+    }
+}
+
+/* Registry of the open streams, keyed by node */
+type streamSet struct {
+    mu      sync.Mutex
+    streams map[string]*signalStream
+}
+
+func newStreamSet() *streamSet {
+    return &streamSet{
+        streams: make(map[string]*signalStream),
+    }
+}
+
+func (set *streamSet) add(ss *signalStream) {
+    set.mu.Lock()
+    defer set.mu.Unlock()
+    set.streams[ss.node] = ss
+}
+
+/* Removes ss unless a newer stream for the same node replaced it */
+func (set *streamSet) remove(ss *signalStream) {
+    set.mu.Lock()
+    defer set.mu.Unlock()
+    if set.streams[ss.node] == ss {
+        delete(set.streams, ss.node)
+    }
+}
+
+func (set *streamSet) get(n string) (*signalStream, bool) {
+    set.mu.Lock()
+    defer set.mu.Unlock()
+    ss, ok := set.streams[n]
+    return ss, ok
+}
+
+func (set *streamSet) all() []*signalStream {
+    set.mu.Lock()
+    defer set.mu.Unlock()
+    streams := make([]*signalStream, 0, len(set.streams))
+    for _, ss := range set.streams {
+        streams = append(streams, ss)
+    }
+    return streams
+}
+
+/*
+Receives signal reports from a remote scheduler and pushes back
+acknowledgements, placement notifications and config updates. The stream
+lives as long as the remote scheduler stays connected
+*/
+func (ctl *CentralScheduler) StreamSignals(stream pb.PodPlacement_StreamSignalsServer) error {
+    first, err := stream.Recv()
+    if err != nil {
+        return err
+    }
+
+    ss := &signalStream{
+        node: first.Node,
+        out:  make(chan *pb.CentralMessage, streamBuffer),
+    }
+    ctl.streams.add(ss)
+    defer ctl.streams.remove(ss)
+
+    log.WithFields(log.Fields{
+        "NODE": ss.node,
+    }).Debug("OPENED SIGNAL STREAM")
+
+    if threshold := ctl.config.Threshold; threshold > 0 {
+        ss.push(&pb.CentralMessage{
+            Message: &pb.CentralMessage_Config{
+                Config: &pb.ConfigUpdate{Threshold: &threshold},
+            },
+        })
+    }
+
+    /* Receives reports on a separate goroutine so sends never block on them */
+    recvErr := make(chan error, 1)
+    go func() {
+        report := first
+        for {
+            ss.push(ctl.handleReport(report))
+
+            var err error
+            report, err = stream.Recv()
+            if err != nil {
+                recvErr <- err
+                return
+            }
+        }
+    }()
+
+    for {
+        select {
+        case err := <-recvErr:
+            log.WithFields(log.Fields{
+                "NODE": ss.node,
+                "ERR":  err,
+            }).Debug("CLOSED SIGNAL STREAM")
+            if err == io.EOF {
+                return nil
+            }
+            return err
+        case msg := <-ss.out:
+            if err := stream.Send(msg); err != nil {
+                return err
+            }
+        }
+    }
+}
+
+/* Records a streamed report and returns the acknowledgement for it */
+func (ctl *CentralScheduler) handleReport(report *pb.SignalReport) *pb.CentralMessage {
+    log.WithFields(log.Fields{
+        "SIGNAL":   report.Signal,
+        "NODE":     report.Node,
+        "SEQUENCE": report.Sequence,
+        "RANK":     report.Rank,
+    }).Debug("RECEIVED STREAMED JOB SIGNAL")
+
+    s := &Signal{
+        Value:    report.Signal,
+        Received: time.Now(),
+        Sequence: report.Sequence,
+    }
+    /* U·Sigma is only usable if its rows follow the schema we project requests through */
+    if m := report.USigma; m != nil && m.SchemaVersion == ctl.config.Schema.Version() &&
+        m.Rows == int64(ctl.config.Schema.D()) && m.Cols > 0 && int64(len(m.Data)) == m.Rows*m.Cols {
+        s.USigma = mat.NewDense(int(m.Rows), int(m.Cols), m.Data)
+    }
+
+    ack := &pb.SignalAck{
+        Sequence: report.Sequence,
+        Accepted: true,
+    }
+    if err := ctl.recordSignal(report.Node, s); err != nil {
+        ack.Accepted = false
+        ack.Reason = err.Error()
+    }
+
+    return &pb.CentralMessage{
+        Message: &pb.CentralMessage_Ack{Ack: ack},
+    }
+}
+
+/* Tells Node n's remote scheduler that Pod p was bound to it */
+func (ctl *CentralScheduler) notifyPlacement(p *v1.Pod, n string, boundAt time.Time) {
+    ss, ok := ctl.streams.get(n)
+    if !ok {
+        return
+    }
+    ss.push(&pb.CentralMessage{
+        Message: &pb.CentralMessage_Placement{
+            Placement: &pb.PlacementNotification{
+                Namespace: p.Namespace,
+                Pod:       p.Name,
+                Node:      n,
+                Timestamp: boundAt.UnixNano(),
+            },
+        },
+    })
+}
+
+/* Pushes a new signal threshold to every connected remote scheduler */
+func (ctl *CentralScheduler) BroadcastThreshold(threshold float64) {
+    for _, ss := range ctl.streams.all() {
+        ss.push(&pb.CentralMessage{
+            Message: &pb.CentralMessage_Config{
+                Config: &pb.ConfigUpdate{Threshold: &threshold},
+            },
+        })
+    }
+}
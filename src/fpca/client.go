@@ -47,6 +47,10 @@ func (fp *FPCAAgent) connectToAgg(aggAddr net.IP) {
 
 }
 
+/*
+Sends the local U·Sigma to the aggregator and returns the aggregate, or nil if
+either side rejected the other's matrix
+*/
 func (fp *FPCAAgent) RequestAgg(m *mat.Dense) (*mat.Dense) {
     log.Debug("FPCA: REQUESTING AGGREGATION")
     ctx := context.Background()
@@ -56,14 +60,26 @@ func (fp *FPCAAgent) RequestAgg(m *mat.Dense) (*mat.Dense) {
         Rows: int64(rows),
         Cols: int64(cols),
         Data: m.RawMatrix().Data,
+        SchemaVersion: fp.schema.Version(),
     })
 
     if err != nil {
 		log.WithFields(log.Fields{
 			"ERROR": err,
-		}).Fatal("FAILED AGGREGATION")
+		}).Error("FAILED AGGREGATION")
+        return nil
 	}
 
+    if uSigma.SchemaVersion != fp.schema.Version() || uSigma.Rows != int64(fp.schema.D()) ||
+        uSigma.Cols <= 0 || int64(len(uSigma.Data)) != uSigma.Rows*uSigma.Cols {
+		log.WithFields(log.Fields{
+			"SCHEMA VERSION": uSigma.SchemaVersion,
+			"ROWS": uSigma.Rows,
+			"COLS": uSigma.Cols,
+		}).Error("REJECTED AGGREGATE")
+        return nil
+    }
+
     log.Debug("FPCA: COMPLETED AGGREGATION")
 
     return mat.NewDense(int(uSigma.Rows), int(uSigma.Cols), uSigma.Data)
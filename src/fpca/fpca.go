@@ -1,27 +1,105 @@
 package fpca
 
 import (
+	"fmt"
+	"math"
 	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	mt "github.com/LucaChot/pronto/src/matrix"
 	pb "github.com/LucaChot/pronto/src/message"
+	"github.com/LucaChot/pronto/src/metrics"
+	"github.com/LucaChot/pronto/src/schema"
+	"github.com/LucaChot/pronto/src/snapshot"
 	"gonum.org/v1/gonum/mat"
 )
 
-const (
-    d = 2
-    r = 2
-)
+/* Rank of the subspace and how it adapts */
+type Config struct {
+    /* Rank r of U and Sigma, the starting rank when Adaptive */
+    Rank     int
+    Adaptive bool
+    /*
+    Bounds on the impact of the r-th singular value, sigma_r over the sum of
+    the first r. Below Alpha the rank shrinks, above Beta it grows, up to the
+    metric dimension
+    */
+    Alpha    float64
+    Beta     float64
+    /* Weights of the local estimate and of each new window B when merging */
+    Weights  mt.MergeWeights
+    /*
+    When positive, the local estimate loses half its weight every HalfLife of
+    sample time instead of being scaled by Weights.HistoryWeight per window,
+    so forgetting does not depend on the window length or hop
+    */
+    HalfLife time.Duration
+    /*
+    Largest principal angle, in radians, U can move between windows without
+    being merged with the aggregate. On a steady node U drifts by about 1e-3
+    to 2e-2 from sampling noise alone, so smaller values report every window
+    */
+    Epsilon  float64
+}
+
+func DefaultConfig() Config {
+    return Config{
+        Rank:     2,
+        Adaptive: false,
+        Alpha:    0.05,
+        Beta:     0.2,
+        Weights:  mt.MergeWeights{
+            HistoryWeight: 0.9,
+            NewDataWeight: 1.1,
+        },
+        HalfLife: 0,
+        Epsilon:  0.02,
+    }
+}
+
+func (c Config) Validate(s schema.Schema) error {
+    if c.Rank < 1 || c.Rank > s.D() {
+        return fmt.Errorf("rank must be between 1 and the metric dimension %d, got %d", s.D(), c.Rank)
+    }
+    if c.Adaptive && (c.Alpha < 0 || c.Beta < c.Alpha) {
+        return fmt.Errorf("rank bounds must satisfy 0 <= alpha <= beta, got %v and %v", c.Alpha, c.Beta)
+    }
+    if c.Weights.HistoryWeight <= 0 || c.Weights.NewDataWeight <= 0 {
+        return fmt.Errorf("merge weights must be positive, got history %v and new data %v",
+            c.Weights.HistoryWeight, c.Weights.NewDataWeight)
+    }
+    if c.HalfLife < 0 {
+        return fmt.Errorf("half-life must not be negative, got %v", c.HalfLife)
+    }
+    if c.Epsilon < 0 || c.Epsilon > math.Pi / 2 {
+        return fmt.Errorf("subspace epsilon must be between 0 and pi/2 radians, got %v", c.Epsilon)
+    }
+    return nil
+}
 
 type USigmaPair struct {
     U *mat.Dense
     Sigma *mat.DiagDense
+    /* Number of components, the columns of U */
+    Rank int
 }
 
 type FPCAAgent struct {
-    inB         <-chan *mat.Dense
+    /* Metric schema ordering the rows of B and U */
+    schema      schema.Schema
+
+    inB         <-chan *metrics.Window
     USIgma      atomic.Pointer[USigmaPair]
+    /* Drift between the last two estimates stored in USIgma */
+    Drift       atomic.Pointer[mt.Drift]
+    /* Receives U and Sigma after every update */
+    snapshots   *snapshot.Publisher
+    epoch       uint64
+    window      uint64
+    /* Sample time of the newest column of b, and of the last merged window */
+    sampledAt   time.Time
+    mergedAt    time.Time
 
     adaptive    bool
 
@@ -33,33 +111,46 @@ type FPCAAgent struct {
     localSigma  *mat.DiagDense
 
     r           int
-    forget      float64
-    enhance     float64
+    weights     mt.MergeWeights
+    halfLife    time.Duration
     alpha       float64
     beta        float64
+    /* Largest principal angle, in radians, U can move without being reported */
     epsilon     float64
 
     aggStub     pb.AggregateMergeClient
 }
 
-func New(ch <-chan *mat.Dense) *FPCAAgent {
+func New(ch <-chan *metrics.Window, s schema.Schema, config Config, snapshots *snapshot.Publisher) *FPCAAgent {
+    if err := config.Validate(s); err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID FPCA CONFIG")
+    }
+
 	fp := FPCAAgent{
+        schema: s,
         inB: ch,
-        adaptive: false,
-        r: r,
-        enhance: 1.1,
-        forget: 0.9,
-        epsilon: 0,
+        snapshots: snapshots,
+        adaptive: config.Adaptive,
+        r: config.Rank,
+        weights: config.Weights,
+        halfLife: config.HalfLife,
+        alpha: config.Alpha,
+        beta: config.Beta,
+        epsilon: config.Epsilon,
     }
 
-    fp.u = mat.NewDense(d, r, nil)
-    fp.sigma = mat.NewDiagDense(r, nil)
+    fp.u = mat.NewDense(s.D(), fp.r, nil)
+    fp.sigma = mat.NewDiagDense(fp.r, nil)
     fp.lastU = fp.u
 
     fp.USIgma.Store(&USigmaPair{
         U: fp.u,
         Sigma: fp.sigma,
+        Rank: fp.r,
     })
+    fp.snapshots.PublishSubspace(fp.epoch, fp.window, fp.u, fp.sigma)
 
     fp.AsClient()
 
@@ -71,34 +162,83 @@ func New(ch <-chan *mat.Dense) *FPCAAgent {
 func (fp *FPCAAgent) RunLocalUpdates() {
     for {
         log.Debug("FPCA: WAITING ON B")
-        fp.b = <-fp.inB
+        w := <-fp.inB
+        fp.b, fp.window, fp.sampledAt = w.B, w.ID, w.Time
         log.Debug("FPCA: RECIEVED B AND BEGINNING FPCA")
 
-		fp.FPCAEdge()
+		if err := fp.FPCAEdge(); err != nil {
+            log.WithFields(log.Fields{
+                "WINDOW": fp.window,
+                "ERROR":  err,
+            }).Warn("FPCA: REJECTED WINDOW")
+            continue
+        }
 
-        if !mat.EqualApprox(fp.u, fp.lastU, fp.epsilon) {
+        if subspaceChanged(fp.u, fp.lastU, fp.epsilon) {
             var uSigma mat.Dense
             uSigma.Mul(fp.u, fp.sigma)
-            aggUSigma := fp.RequestAgg(&uSigma)
-            fp.u, fp.sigma = mt.AggMerge(aggUSigma, &uSigma, fp.r)
+            /* Keep the local estimate if the aggregator rejected it */
+            if aggUSigma := fp.RequestAgg(&uSigma); aggUSigma != nil {
+                u, sigma, err := mt.AggMerge(aggUSigma, &uSigma, fp.r)
+                if err != nil {
+                    log.WithFields(log.Fields{
+                        "ERROR": err,
+                    }).Warn("FPCA: FAILED TO MERGE AGGREGATE")
+                } else {
+                    fp.u, fp.sigma = u, sigma
+                }
+            }
         }
 
+        prev := fp.USIgma.Load()
         fp.USIgma.Store(&USigmaPair{
             U: fp.u,
             Sigma: fp.sigma,
+            Rank: fp.r,
         })
-        log.Debug("FPCA: UPDATED U AND SIGMA")
+        fp.recordDrift(prev)
+        fp.epoch++
+        fp.snapshots.PublishSubspace(fp.epoch, fp.window, fp.u, fp.sigma)
+        log.WithFields(log.Fields{
+            "EPOCH":  fp.epoch,
+            "WINDOW": fp.window,
+            "RANK":   fp.r,
+        }).Debug("FPCA: UPDATED U AND SIGMA")
 
         fp.lastU = fp.u
     }
 }
 
+/* Records how far the current U Sigma moved from the estimate prev */
+func (fp *FPCAAgent) recordDrift(prev *USigmaPair) {
+    var prevUSigma, uSigma mat.Dense
+    prevUSigma.Mul(prev.U, prev.Sigma)
+    uSigma.Mul(fp.u, fp.sigma)
+
+    drift, err := mt.SubspaceDrift(&prevUSigma, &uSigma)
+    if err != nil {
+        log.WithFields(log.Fields{
+            "ERROR": err,
+        }).Warn("FPCA: FAILED TO MEASURE DRIFT")
+        return
+    }
+    fp.Drift.Store(&drift)
+    log.WithFields(log.Fields{
+        "CHORDAL":            drift.Chordal,
+        "PROJECTION":         drift.Projection,
+        "EXPLAINED VARIANCE": drift.ExplainedVariance,
+    }).Debug("FPCA: SUBSPACE DRIFT")
+}
+
 /*
 TODO: Ask andreas about the pseudocode of the paper, in the rank function, it assumes
 that the sigma is of size r x r, so what does Sigma_[r+1] do?
 TODO: Ask which version of Merge should I implement
+
+Returns an error, leaving the estimates as they were, if B cannot be merged,
+e.g. because it holds a NaN
 */
-func (fp *FPCAAgent) FPCAEdge() {
+func (fp *FPCAAgent) FPCAEdge() error {
     /*
     * Update embedding estimates *
     if mc.localU, mc.localSigma = 0,0:
@@ -113,25 +253,92 @@ func (fp *FPCAAgent) FPCAEdge() {
     mc.GlobalU, mc.GlobalSigma := Rank(mc.GlobalU, mc.GlobalSigma, alpha, beta, r)
     */
 
+    var localU *mat.Dense
+    var localSigma *mat.DiagDense
+    var err error
     if fp.localU == nil && fp.localSigma == nil {
-        fp.localU, fp.localSigma = mt.SVDR(fp.b, fp.r)
+        localU, localSigma, err = mt.SVDR(fp.b, fp.r)
     } else {
         _, bc := fp.b.Dims()
-        identity := mat.NewDiagDense(bc, nil)
-        fp.localU, fp.localSigma = mt.Merge(fp.localU, fp.localSigma, fp.b, identity, fp.r, fp.enhance, fp.forget)
+        ones := make([]float64, bc)
+        for i := range ones {
+            ones[i] = 1
+        }
+        identity := mat.NewDiagDense(bc, ones)
+        localU, localSigma, err = mt.Merge(fp.localU, fp.localSigma, fp.b, identity, fp.r, fp.mergeWeights())
+    }
+    if err != nil {
+        return err
     }
 
     var uSigma, localUSigma mat.Dense
     uSigma.Mul(fp.u, fp.sigma)
-    localUSigma.Mul(fp.localU, fp.localSigma)
+    localUSigma.Mul(localU, localSigma)
 
 
     if fp.adaptive {
-        /* Pass in rank r+1 so that we can increase the rank in the next step */
-        tempU, tempSigma := mt.AggMerge(&uSigma, &localUSigma, fp.r + 1)
-        fp.u, fp.sigma = mt.Rank(tempU, tempSigma, fp.r, fp.alpha, fp.beta)
-    } else {
-        fp.u, fp.sigma = mt.AggMerge(&uSigma, &localUSigma, fp.r)
+        /*
+        Pass in rank r+1 so that we can increase the rank in the next step,
+        unless r already spans every metric dimension
+        */
+        tempU, tempSigma, err := mt.AggMerge(&uSigma, &localUSigma, min(fp.r + 1, fp.schema.D()))
+        if err != nil {
+            return err
+        }
+        u, sigma, err := mt.Rank(tempU, tempSigma, fp.r, fp.alpha, fp.beta)
+        if err != nil {
+            return err
+        }
+        fp.localU, fp.localSigma, fp.u, fp.sigma = localU, localSigma, u, sigma
+        fp.mergedAt = fp.sampledAt
+
+        if _, rank := fp.u.Dims(); rank != fp.r {
+            log.WithFields(log.Fields{
+                "FROM": fp.r,
+                "TO":   rank,
+            }).Debug("FPCA: CHANGED RANK")
+            fp.r = rank
+        }
+        return nil
     }
+
+    u, sigma, err := mt.AggMerge(&uSigma, &localUSigma, fp.r)
+    if err != nil {
+        return err
+    }
+    fp.localU, fp.localSigma, fp.u, fp.sigma = localU, localSigma, u, sigma
+    fp.mergedAt = fp.sampledAt
+    return nil
+}
+
+/*
+Weights for merging b into the local estimate. With a half-life, the history
+weight is 0.5^(elapsed / HalfLife), elapsed being the sample time since the
+last merged window
+*/
+func (fp *FPCAAgent) mergeWeights() mt.MergeWeights {
+    weights := fp.weights
+    if fp.halfLife > 0 {
+        elapsed := max(fp.sampledAt.Sub(fp.mergedAt), 0)
+        weights.HistoryWeight = math.Pow(0.5, elapsed.Seconds() / fp.halfLife.Seconds())
+    }
+    return weights
 }
 
+/*
+Reports whether span(U) moved by a principal angle of more than epsilon, or
+changed rank. Comparing the spans rather than the entries ignores sign flips
+and rotations within the subspace
+*/
+func subspaceChanged(u, lastU *mat.Dense, epsilon float64) bool {
+    ur, uc := u.Dims()
+    lr, lc := lastU.Dims()
+    if ur != lr || uc != lc {
+        return true
+    }
+    angles, err := mt.PrincipalAngles(u, lastU)
+    if err != nil {
+        return true
+    }
+    return angles[len(angles) - 1] > epsilon
+}
@@ -0,0 +1,69 @@
+package matrix
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+    /* An input matrix was nil */
+    ErrNilMatrix     = errors.New("matrix: nil matrix")
+    /* An input matrix held a NaN or infinite value */
+    ErrNotFinite     = errors.New("matrix: NaN or infinite value")
+    /* Sigma held a negative singular value */
+    ErrNegativeSigma = errors.New("matrix: negative singular value")
+    /* The SVD did not converge */
+    ErrNoConvergence = errors.New("matrix: SVD did not converge")
+)
+
+/* A rank outside what the matrices of an operation can hold */
+type RankError struct {
+    Op   string
+    Rank int
+    /* Largest rank the inputs allow */
+    Max  int
+}
+
+func (e *RankError) Error() string {
+    return fmt.Sprintf("matrix: %s: rank %d is outside 1 to %d", e.Op, e.Rank, e.Max)
+}
+
+/* Matrices whose dimensions do not fit together */
+type DimensionError struct {
+    Op     string
+    Detail string
+}
+
+func (e *DimensionError) Error() string {
+    return fmt.Sprintf("matrix: %s: %s", e.Op, e.Detail)
+}
+
+func dimensionError(op, format string, args ...any) error {
+    return &DimensionError{
+        Op:     op,
+        Detail: fmt.Sprintf(format, args...),
+    }
+}
+
+func checkRank(op string, r, max int) error {
+    if r < 1 || r > max {
+        return &RankError{Op: op, Rank: r, Max: max}
+    }
+    return nil
+}
+
+/* Returns ErrNotFinite, wrapped with op, if m holds a NaN or infinity */
+func checkFinite(op string, m mat.Matrix) error {
+    rows, cols := m.Dims()
+    for i := range rows {
+        for j := range cols {
+            if v := m.At(i, j); math.IsNaN(v) || math.IsInf(v, 0) {
+                return fmt.Errorf("%s: %w at (%d, %d)", op, ErrNotFinite, i, j)
+            }
+        }
+    }
+    return nil
+}
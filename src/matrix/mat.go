@@ -6,42 +6,57 @@ import (
 	"gonum.org/v1/gonum/mat"
 )
 
-// TODO: Add assert statements that ensure the correct values are returned
 /*
 Function Structure
-- Assertions
+- Assertions, returning a RankError, DimensionError or one of the Err values
 - Calculation
 - Handle Subsequent Errors
 */
 
 
-func SVDR(b mat.Matrix, r int) (*mat.Dense, *mat.DiagDense) {
+/*
+Rank r SVD of b. The signs of U are canonicalised so that the largest-magnitude
+entry of each column is positive
+*/
+func SVDR(b mat.Matrix, r int) (*mat.Dense, *mat.DiagDense, error) {
+    if b == nil {
+        return nil, nil, ErrNilMatrix
+    }
     br, bc := b.Dims()
-    if min(br, bc) < r {
-		panic(fmt.Errorf("rank r must be smaller than the dimensions of matrix"))
+    if err := checkRank("SVDR", r, min(br, bc)); err != nil {
+        return nil, nil, err
 	}
+    if err := checkFinite("SVDR", b); err != nil {
+        return nil, nil, err
+    }
 
     var svd mat.SVD
-    svd.Factorize(b, mat.SVDThinU)
+    if !svd.Factorize(b, mat.SVDThinU) {
+        return nil, nil, ErrNoConvergence
+    }
 
     var fullU, u mat.Dense
     svd.UTo(&fullU)
     m, _ := fullU.Dims()
     u.CloneFrom(fullU.Slice(0, m, 0, r))
+    canonicaliseSigns(&u)
 
     sData := svd.Values(nil)
     sigma := mat.NewDiagDense(r, sData[:r])
 
-    return &u, sigma
+    return &u, sigma, nil
 }
 
 /* Concatenates two matrices A and B */
-func Concatenate(A, B *mat.Dense) (*mat.Dense) {
+func Concatenate(A, B *mat.Dense) (*mat.Dense, error) {
+    if A == nil || B == nil {
+        return nil, ErrNilMatrix
+    }
     ar, ac := A.Dims()
     br, bc := B.Dims()
 
     if ar != br {
-		panic(fmt.Errorf("input matrices must have the same number of rows"))
+        return nil, dimensionError("Concatenate", "input matrices have %d and %d rows", ar, br)
 	}
 
     /* Creates matrix storing the concatenation */
@@ -62,10 +77,27 @@ func Concatenate(A, B *mat.Dense) (*mat.Dense) {
         copy(destCRowSlice[ac:], srcBRowSlice)
     }
 
-    return C
+    return C, nil
+}
+
+/*
+Scales applied to the two blocks of a Merge. Weights below 1 forget a block,
+weights above 1 enhance it
+*/
+type MergeWeights struct {
+    /* Scales U1 Sigma1, the estimate so far */
+    HistoryWeight float64
+    /* Scales U2 Sigma2, the data being merged in */
+    NewDataWeight float64
 }
 
-func Merge(U1 mat.Matrix, Sigma1 mat.Matrix, U2 mat.Matrix, Sigma2 mat.Matrix, r int, forget float64, enhance float64) (*mat.Dense, *mat.DiagDense) {
+/*
+Rank r SVD of [w.HistoryWeight * U1 Sigma1, w.NewDataWeight * U2 Sigma2].
+When U1 has orthonormal columns, as returned by SVDR, it is used as the basis
+of the incremental merge directly; otherwise the first block is orthogonalised
+first
+*/
+func Merge(U1 mat.Matrix, Sigma1 mat.Matrix, U2 mat.Matrix, Sigma2 mat.Matrix, r int, w MergeWeights) (*mat.Dense, *mat.DiagDense, error) {
     /*
     Z = U_1.transpose() * U_2
     Q, R = QR(U_2 - (U_1 * Z))
@@ -74,45 +106,125 @@ func Merge(U1 mat.Matrix, Sigma1 mat.Matrix, U2 mat.Matrix, Sigma2 mat.Matrix, r
 
     U'' = [U_1, Q]U'
     */
-    var temp1 mat.Dense
-    temp1.Mul(U1, Sigma1)
-    temp1.Scale(forget, &temp1)
+    if U1 == nil || Sigma1 == nil || U2 == nil || Sigma2 == nil {
+        return nil, nil, ErrNilMatrix
+    }
+    d, c1 := U1.Dims()
+    d2, c2 := U2.Dims()
+    if d != d2 {
+        return nil, nil, dimensionError("Merge", "U1 and U2 have %d and %d rows", d, d2)
+    }
+    if err := checkRank("Merge", r, min(d, c1 + c2)); err != nil {
+        return nil, nil, err
+    }
+
+    if w.HistoryWeight < 0 || w.NewDataWeight < 0 {
+        return nil, nil, fmt.Errorf("matrix: Merge: weights must not be negative, got %v and %v",
+            w.HistoryWeight, w.NewDataWeight)
+    }
+
+    temp2, err := scaledProduct("Merge", U2, Sigma2, w.NewDataWeight)
+    if err != nil {
+        return nil, nil, err
+    }
+    if err := checkFinite("Merge", temp2); err != nil {
+        return nil, nil, err
+    }
+
+    if isOrthonormal(U1) {
+        if sr, sc := Sigma1.Dims(); sr != c1 || sc != c1 {
+            return nil, nil, dimensionError("Merge", "Sigma1 is %dx%d for %d columns of U1", sr, sc, c1)
+        }
+        var m1 mat.Dense
+        m1.Scale(w.HistoryWeight, Sigma1)
+        if err := checkFinite("Merge", &m1); err != nil {
+            return nil, nil, err
+        }
+        return incrementalSVD(mat.DenseCopyOf(U1), &m1, temp2, r)
+    }
+
+    temp1, err := scaledProduct("Merge", U1, Sigma1, w.HistoryWeight)
+    if err != nil {
+        return nil, nil, err
+    }
+    if err := checkFinite("Merge", temp1); err != nil {
+        return nil, nil, err
+    }
 
-    var temp2 mat.Dense
-    temp2.Mul(U2, Sigma2)
-    temp2.Scale(enhance, &temp2)
+    Q1, M1 := orthogonalise(nil, temp1)
+    if Q1 == nil {
+        return incrementalSVD(nil, nil, temp2, r)
+    }
+    return incrementalSVD(Q1, M1, temp2, r)
+}
 
-    concat := Concatenate(&temp1, &temp2)
+/*
+Returns s * U * Sigma. A diagonal Sigma scales the columns of U directly,
+which avoids a general matrix product
+*/
+func scaledProduct(op string, U, Sigma mat.Matrix, s float64) (*mat.Dense, error) {
+    ur, uc := U.Dims()
+    if sr, _ := Sigma.Dims(); sr != uc {
+        return nil, dimensionError(op, "Sigma has %d rows for %d columns of U", sr, uc)
+    }
 
-    return SVDR(concat, r)
+    diag, ok := Sigma.(mat.Diagonal)
+    if !ok {
+        var out mat.Dense
+        out.Mul(U, Sigma)
+        out.Scale(s, &out)
+        return &out, nil
+    }
+
+    out := mat.DenseCopyOf(U)
+    raw := out.RawMatrix()
+    for i := range ur {
+        row := raw.Data[i*raw.Stride : i*raw.Stride + uc]
+        for j := range row {
+            row[j] *= s * diag.At(j, j)
+        }
+    }
+    return out, nil
 }
 
-func ImpactOfRank(Sigma *mat.DiagDense, r int) (float64) {
+/* Share of the first r singular values held by the r-th */
+func ImpactOfRank(Sigma *mat.DiagDense, r int) (float64, error) {
     if Sigma == nil {
-		panic(fmt.Errorf("input matrix Sigma is nil"))
+        return 0, ErrNilMatrix
 	}
     sr, _ := Sigma.Dims()
-    if r < 1 || sr < r {
-		panic(fmt.Errorf("rank is out of bounds"))
+    if err := checkRank("ImpactOfRank", r, sr); err != nil {
+        return 0, err
+    }
+    if err := checkFinite("ImpactOfRank", Sigma); err != nil {
+        return 0, err
     }
 
-    diagData := Sigma.RawBand().Data
     var total float64
     for i := range r {
-        total += diagData[i]
+        v := Sigma.At(i, i)
+        if v < 0 {
+            return 0, ErrNegativeSigma
+        }
+        total += v
     }
 
+    /* Singular values are non-negative, so only all-zero sums to zero */
     if total == 0 {
-        if diagData[r-1] == 0 {
-            return 0
-        }
-		panic(fmt.Errorf("division by zero: sum of first %d diagonal elements is zero", r))
+        return 0, nil
     }
 
-    return diagData[r-1] / total
+    return Sigma.At(r-1, r-1) / total, nil
 }
 
-func Rank(inU *mat.Dense, inSigma *mat.DiagDense, r int, alpha, beta float64) (*mat.Dense, *mat.DiagDense) {
+/*
+Adjusts rank r from the impact of the r-th singular value. inU and inSigma
+may carry r+1 components, as FPCAEdge merges at rank r+1, in which case
+growing the rank keeps the extra one; otherwise U is extended with the
+canonical vector furthest from its span and a zero singular value. The rank
+stays between 1 and the number of rows of U, the metric dimension
+*/
+func Rank(inU *mat.Dense, inSigma *mat.DiagDense, r int, alpha, beta float64) (*mat.Dense, *mat.DiagDense, error) {
     /*
     total_variance = sum(variance(Sigma,i))
     Epsilon = variance(Sigma, r) / total_variance
@@ -125,25 +237,35 @@ func Rank(inU *mat.Dense, inSigma *mat.DiagDense, r int, alpha, beta float64) (*
         [U,e], Sigma[:r+1]
     */
 
+    if inU == nil || inSigma == nil {
+        return nil, nil, ErrNilMatrix
+    }
     ur, uc := inU.Dims()
-    _, sc := inU.Dims()
+    sc, _ := inSigma.Dims()
 
-    // Assume r < U.Cols(), Sigma.Cols()
-    if uc <= r || sc <= r {
-        panic(fmt.Errorf("r must be smaller than the number of columns in U"))
+    if uc != sc {
+        return nil, nil, dimensionError("Rank", "U has %d components and Sigma %d", uc, sc)
+    }
+    if err := checkRank("Rank", r, uc); err != nil {
+        return nil, nil, err
     }
 
-    impact := ImpactOfRank(inSigma, r)
-    var rank int
+    impact, err := ImpactOfRank(inSigma, r)
+    if err != nil {
+        return nil, nil, err
+    }
+    rank := r
     if impact < alpha {
-        rank = r-1
-    } else if impact <= beta {
-        rank = r
-    } else {
-        /* TODO: Complete with understanding from Andreas */
-        rank = r+1
+        rank = max(r-1, 1)
+    } else if impact > beta {
+        rank = min(r+1, ur)
+    }
+
+    if rank > uc {
+        return extendRank(inU, inSigma)
     }
 
+
     var outU mat.Dense
     outU.CloneFrom(inU.Slice(0, ur, 0, rank))
 
@@ -151,12 +273,52 @@ func Rank(inU *mat.Dense, inSigma *mat.DiagDense, r int, alpha, beta float64) (*
     copy(outDiag, inSigma.RawBand().Data[:rank])
     outSigma := mat.NewDiagDense(rank, outDiag)
 
+    return &outU, outSigma, nil
+}
 
+/*
+Appends to U the canonical vector e_i with the largest component outside the
+span of U, orthonormalised against U, along with a zero singular value. U must
+have fewer columns than rows
+*/
+func extendRank(inU *mat.Dense, inSigma *mat.DiagDense) (*mat.Dense, *mat.DiagDense, error) {
+    ur, uc := inU.Dims()
+    if err := checkRank("extendRank", uc + 1, ur); err != nil {
+        return nil, nil, err
+    }
 
-    return &outU, outSigma
+    /* Residual of e_i after projecting onto U is e_i - U * U[i,:]^T */
+    var best *mat.VecDense
+    bestNorm := -1.0
+    for i := range ur {
+        residual := mat.NewVecDense(ur, nil)
+        residual.MulVec(inU, inU.RowView(i))
+        residual.ScaleVec(-1, residual)
+        residual.SetVec(i, residual.AtVec(i) + 1)
+
+        if norm := residual.Norm(2); norm > bestNorm {
+            best, bestNorm = residual, norm
+        }
+    }
+    best.ScaleVec(1/bestNorm, best)
+
+    outU := mat.NewDense(ur, uc+1, nil)
+    outU.Slice(0, ur, 0, uc).(*mat.Dense).Copy(inU)
+    outU.SetCol(uc, best.RawVector().Data)
+    canonicaliseSigns(outU)
+
+    outDiag := make([]float64, uc+1)
+    copy(outDiag, inSigma.RawBand().Data[:uc])
+    outSigma := mat.NewDiagDense(uc+1, outDiag)
+
+    return outU, outSigma, nil
 }
 
-func AggMerge(USigma1 *mat.Dense, USigma2 *mat.Dense, r int) (*mat.Dense, *mat.DiagDense) {
+/*
+Rank r SVD of [USigma1, USigma2], computed incrementally; ConcatSVD computes
+the same by factorizing the concatenation
+*/
+func AggMerge(USigma1 *mat.Dense, USigma2 *mat.Dense, r int) (*mat.Dense, *mat.DiagDense, error) {
     /*
     Z = U_1.transpose() * U_2
     Q, R = QR(U_2 - (U_1 * Z))
@@ -164,24 +326,28 @@ func AggMerge(USigma1 *mat.Dense, USigma2 *mat.Dense, r int) (*mat.Dense, *mat.D
     U', Sigma'' = SVD( [[Sigma_1, Z * Sigma_2], [0, R * Sigma_2]], r)
 
     U'' = [U_1, Q]U'
-
-    Different Version:
-    var Z *mat.Dense
-    Z.Mul(U_1.T(), U_2)
-
-
-    var MultU1AndZ, SubtractU2AndMult *mat.Dense
-    MultU1AndZ.Mul(U_1, Z)
-    SubtractU2AndMult.Sub(U_2, MultU1AndZ)
-
-    var qr mat.QR
-    var Q, R *mat.Dense
-    qr.Factorize(SubtractU2AndMult)
-    qr.QTo(Q)
-    qr.RTo(R)
     */
-    concat := Concatenate(USigma1, USigma2)
-
-    return SVDR(concat, r)
+    if USigma1 == nil || USigma2 == nil {
+        return nil, nil, ErrNilMatrix
+    }
+    ar, ac := USigma1.Dims()
+    br, bc := USigma2.Dims()
+    if ar != br {
+        return nil, nil, dimensionError("AggMerge", "input matrices have %d and %d rows", ar, br)
+	}
+    if err := checkRank("AggMerge", r, min(ar, ac + bc)); err != nil {
+        return nil, nil, err
+    }
+    if err := checkFinite("AggMerge", USigma1); err != nil {
+        return nil, nil, err
+    }
+    if err := checkFinite("AggMerge", USigma2); err != nil {
+        return nil, nil, err
+    }
 
+    Q1, M1 := orthogonalise(nil, USigma1)
+    if Q1 == nil {
+        return incrementalSVD(nil, nil, USigma2, r)
+    }
+    return incrementalSVD(Q1, M1, USigma2, r)
 }
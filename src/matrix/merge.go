@@ -0,0 +1,199 @@
+package matrix
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+Columns whose component outside the basis is below this fraction of the
+largest column norm are treated as lying in the span of the basis
+*/
+const spanTolerance = 1e-10
+
+/*
+Reference merge: SVD of the concatenation [X1, X2]. Factorizes the full
+d x (c1+c2) matrix, so it is only used to check the incremental merge
+*/
+func ConcatSVD(X1, X2 *mat.Dense, r int) (*mat.Dense, *mat.DiagDense, error) {
+    concat, err := Concatenate(X1, X2)
+    if err != nil {
+        return nil, nil, err
+    }
+    return SVDR(concat, r)
+}
+
+/*
+Extends the orthonormal columns of Q (nil for none) with orthonormal columns
+spanning the part of X outside span(Q), using modified Gram-Schmidt with one
+reorthogonalisation pass. Returns the new columns Qn, nil if there are none,
+and the coefficients C such that X = [Q, Qn] C, nil if Q and X are both empty
+*/
+func orthogonalise(Q *mat.Dense, X mat.Matrix) (*mat.Dense, *mat.Dense) {
+    xr, xc := X.Dims()
+
+    /* Columns are kept as contiguous slices to avoid strided access */
+    var basis [][]float64
+    if Q != nil {
+        /* Callers pass a Q with as many rows as X */
+        _, qc := Q.Dims()
+        for i := range qc {
+            basis = append(basis, mat.Col(nil, i, Q))
+        }
+    }
+    k := len(basis)
+
+    cols := make([][]float64, xc)
+    var scale float64
+    for j := range xc {
+        cols[j] = mat.Col(nil, j, X)
+        scale = max(scale, floats.Norm(cols[j], 2))
+    }
+
+    coefs := make([][]float64, xc)
+    for j, v := range cols {
+        coef := make([]float64, len(basis) + 1)
+        for range 2 {
+            for i, b := range basis {
+                c := floats.Dot(b, v)
+                coef[i] += c
+                floats.AddScaled(v, -c, b)
+            }
+        }
+
+        norm := floats.Norm(v, 2)
+        if len(basis) < xr && scale > 0 && norm > spanTolerance * scale {
+            floats.Scale(1/norm, v)
+            basis = append(basis, v)
+            coef[len(basis)-1] = norm
+        } else {
+            coef = coef[:len(basis)]
+        }
+        coefs[j] = coef
+    }
+
+    if len(basis) == 0 {
+        /* X and the basis are both empty */
+        return nil, nil
+    }
+    C := mat.NewDense(len(basis), xc, nil)
+    for j, coef := range coefs {
+        for i, c := range coef {
+            C.Set(i, j, c)
+        }
+    }
+
+    kn := len(basis) - k
+    if kn == 0 {
+        return nil, C
+    }
+    Qn := mat.NewDense(xr, kn, nil)
+    for i, b := range basis[k:] {
+        Qn.SetCol(i, b)
+    }
+    return Qn, C
+}
+
+/*
+Rank r SVD of [Q1 M1, X2] where Q1 has orthonormal columns:
+
+    Z = Q1^T X2
+    Q2, R2 = QR(X2 - Q1 Z)
+    U', Sigma = SVD([[M1, Z], [0, R2]], r)
+    U = [Q1, Q2] U'
+
+Only the (k1+k2) x (c1+c2) matrix in the middle is factorized by SVD, where k1
+and k2 are the number of columns of Q1 and Q2. Q1 and M1 are nil when the
+first block is zero. If fewer than r components exist, U is completed with
+canonical directions and zero singular values. The signs of U are
+canonicalised as in SVDR. Callers check the dimensions
+and that r is at most min(d, c1+c2)
+*/
+func incrementalSVD(Q1 *mat.Dense, M1 mat.Matrix, X2 mat.Matrix, r int) (*mat.Dense, *mat.DiagDense, error) {
+    d, c2 := X2.Dims()
+    var k1, c1 int
+    if Q1 != nil {
+        _, k1 = Q1.Dims()
+        _, c1 = M1.Dims()
+    }
+
+    Q2, C2 := orthogonalise(Q1, X2)
+    k := k1
+    if Q2 != nil {
+        _, k2 := Q2.Dims()
+        k += k2
+    }
+    if k == 0 {
+        /* Both blocks are zero */
+        return canonicalBasis(d, r), mat.NewDiagDense(r, nil), nil
+    }
+
+    /* K = [[M1, Z], [0, R2]], Z and R2 stacked in C2 */
+    K := mat.NewDense(k, c1 + c2, nil)
+    if k1 > 0 {
+        K.Slice(0, k1, 0, c1).(*mat.Dense).Copy(M1)
+    }
+    K.Slice(0, k, c1, c1 + c2).(*mat.Dense).Copy(C2)
+
+    var svd mat.SVD
+    if !svd.Factorize(K, mat.SVDThinU) {
+        return nil, nil, ErrNoConvergence
+    }
+    var uCore mat.Dense
+    svd.UTo(&uCore)
+    values := svd.Values(nil)
+
+    basis := Q1
+    if Q1 == nil {
+        basis = Q2
+    } else if Q2 != nil {
+        basis = mat.NewDense(d, k, nil)
+        basis.Slice(0, d, 0, k1).(*mat.Dense).Copy(Q1)
+        basis.Slice(0, d, k1, k).(*mat.Dense).Copy(Q2)
+    }
+
+    n := min(r, len(values))
+    var u mat.Dense
+    u.Mul(basis, uCore.Slice(0, k, 0, n))
+    canonicaliseSigns(&u)
+    sigma := mat.NewDiagDense(n, append([]float64(nil), values[:n]...))
+
+    U := &u
+    for ; n < r; n++ {
+        var err error
+        if U, sigma, err = extendRank(U, sigma); err != nil {
+            return nil, nil, err
+        }
+    }
+    return U, sigma, nil
+}
+
+/* The first r columns of the d x d identity */
+func canonicalBasis(d, r int) *mat.Dense {
+    U := mat.NewDense(d, r, nil)
+    for i := range r {
+        U.Set(i, i, 1)
+    }
+    return U
+}
+
+/* Reports whether the columns of U are orthonormal */
+func isOrthonormal(U mat.Matrix) bool {
+    _, uc := U.Dims()
+    var gram mat.Dense
+    gram.Mul(U.T(), U)
+    for i := range uc {
+        for j := range uc {
+            want := 0.0
+            if i == j {
+                want = 1
+            }
+            if math.Abs(gram.At(i, j) - want) > 1e-9 {
+                return false
+            }
+        }
+    }
+    return true
+}
@@ -0,0 +1,245 @@
+package matrix
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/* Relative tolerance of the incremental merge against ConcatSVD */
+const mergeTolerance = 1e-9
+
+/* Kinds of block the property tests merge */
+const (
+    randomBlock = iota
+    zeroBlock
+    /* Columns are combinations of the other block's columns */
+    dependentBlock
+)
+
+var blockNames = []string{"random", "zero", "dependent"}
+
+func randomDense(rng *rand.Rand, r, c int) *mat.Dense {
+    m := mat.NewDense(r, c, nil)
+    for i := range r {
+        for j := range c {
+            m.Set(i, j, rng.NormFloat64())
+        }
+    }
+    return m
+}
+
+/* Builds a d x c block of the given kind, dependent blocks lie in span(other) */
+func block(rng *rand.Rand, kind, d, c int, other *mat.Dense) *mat.Dense {
+    switch kind {
+    case zeroBlock:
+        return mat.NewDense(d, c, nil)
+    case dependentBlock:
+        _, oc := other.Dims()
+        var m mat.Dense
+        m.Mul(other, randomDense(rng, oc, c))
+        return &m
+    }
+    return randomDense(rng, d, c)
+}
+
+/*
+Checks that U, Sigma matches the reference: equal singular values and equal
+U Sigma^2 U^T. The latter is only unique when sigma_r is separated from the
+next singular value of the input, which full is the reference of
+*/
+func compareFactorisations(t *testing.T, name string, U *mat.Dense, Sigma *mat.DiagDense,
+    refU *mat.Dense, refSigma *mat.DiagDense, full []float64) {
+    t.Helper()
+
+    r, _ := Sigma.Dims()
+    if rr, _ := refSigma.Dims(); rr != r {
+        t.Fatalf("%s: rank %d, reference rank %d", name, r, rr)
+    }
+    if ur, uc := U.Dims(); uc != r {
+        t.Fatalf("%s: U is %dx%d for rank %d", name, ur, uc, r)
+    }
+
+    scale := math.Max(full[0], 1)
+    for i := range r {
+        if diff := math.Abs(Sigma.At(i, i) - refSigma.At(i, i)); diff > mergeTolerance * scale {
+            t.Errorf("%s: sigma_%d is %v, reference %v", name, i, Sigma.At(i, i), refSigma.At(i, i))
+        }
+    }
+
+    if r < len(full) && full[r-1] - full[r] < 1e-6 * scale {
+        return
+    }
+    var got, want, us, refUs mat.Dense
+    us.Mul(U, Sigma)
+    got.Mul(&us, us.T())
+    refUs.Mul(refU, refSigma)
+    want.Mul(&refUs, refUs.T())
+    if !mat.EqualApprox(&got, &want, mergeTolerance * scale * scale) {
+        t.Errorf("%s: U Sigma^2 U^T differs from the reference by %v", name,
+            maxAbsDiff(&got, &want))
+    }
+}
+
+func maxAbsDiff(a, b *mat.Dense) float64 {
+    var diff mat.Dense
+    diff.Sub(a, b)
+    return mat.Norm(&diff, math.Inf(1))
+}
+
+/* Singular values of [X1, X2], largest first */
+func concatValues(t *testing.T, X1, X2 *mat.Dense) []float64 {
+    t.Helper()
+    concat, err := Concatenate(X1, X2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var svd mat.SVD
+    if !svd.Factorize(concat, mat.SVDNone) {
+        t.Fatal("reference SVD did not converge")
+    }
+    return svd.Values(nil)
+}
+
+type mergeCase struct {
+    d, c1, c2, r int
+    kind1, kind2 int
+}
+
+func (mc mergeCase) String() string {
+    return fmt.Sprintf("d=%d c1=%d c2=%d r=%d %s/%s", mc.d, mc.c1, mc.c2, mc.r,
+        blockNames[mc.kind1], blockNames[mc.kind2])
+}
+
+/* Random cases over d from 2 to 40, mixed ranks and every pair of block kinds */
+func mergeCases(rng *rand.Rand) []mergeCase {
+    var cases []mergeCase
+    for _, d := range []int{2, 3, 5, 8, 16, 32, 40} {
+        for kind1 := range blockNames {
+            for kind2 := range blockNames {
+                for range 4 {
+                    c1, c2 := 1 + rng.Intn(d), 1 + rng.Intn(d)
+                    r := 1 + rng.Intn(min(d, c1 + c2))
+                    cases = append(cases, mergeCase{d, c1, c2, r, kind1, kind2})
+                }
+            }
+        }
+    }
+    return cases
+}
+
+/*
+Builds X1 and X2 for a case. A dependent X1 lies in the span of X2, which is
+random if both are dependent
+*/
+func (mc mergeCase) blocks(rng *rand.Rand) (*mat.Dense, *mat.Dense) {
+    if mc.kind1 == dependentBlock {
+        kind2 := mc.kind2
+        if kind2 == dependentBlock {
+            kind2 = randomBlock
+        }
+        X2 := block(rng, kind2, mc.d, mc.c2, nil)
+        return block(rng, dependentBlock, mc.d, mc.c1, X2), X2
+    }
+    X1 := block(rng, mc.kind1, mc.d, mc.c1, nil)
+    return X1, block(rng, mc.kind2, mc.d, mc.c2, X1)
+}
+
+func TestAggMergeMatchesConcatSVD(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    for _, mc := range mergeCases(rng) {
+        X1, X2 := mc.blocks(rng)
+
+        U, Sigma, err := AggMerge(X1, X2, mc.r)
+        if err != nil {
+            t.Fatalf("%v: AggMerge: %v", mc, err)
+        }
+        refU, refSigma, err := ConcatSVD(X1, X2, mc.r)
+        if err != nil {
+            t.Fatalf("%v: ConcatSVD: %v", mc, err)
+        }
+        compareFactorisations(t, mc.String(), U, Sigma, refU, refSigma, concatValues(t, X1, X2))
+    }
+}
+
+func TestMergeMatchesConcatSVD(t *testing.T) {
+    rng := rand.New(rand.NewSource(2))
+    weights := MergeWeights{HistoryWeight: 0.9, NewDataWeight: 1.1}
+    for _, mc := range mergeCases(rng) {
+        X1, X2 := mc.blocks(rng)
+        U2, Sigma2 := X2, mat.NewDiagDense(mc.c2, nil)
+        for i := range mc.c2 {
+            Sigma2.SetDiag(i, 1)
+        }
+
+        /* U1 from SVDR takes the orthonormal path, X1 itself does not */
+        k1 := min(mc.d, mc.c1)
+        svdU1, svdSigma1, err := SVDR(X1, k1)
+        if err != nil {
+            t.Fatalf("%v: SVDR: %v", mc, err)
+        }
+        ones := mat.NewDiagDense(mc.c1, nil)
+        for i := range mc.c1 {
+            ones.SetDiag(i, 1)
+        }
+
+        for _, first := range []struct {
+            name  string
+            U     *mat.Dense
+            Sigma *mat.DiagDense
+        }{
+            {"orthonormal", svdU1, svdSigma1},
+            {"general", X1, ones},
+        } {
+            name := fmt.Sprintf("%v %s", mc, first.name)
+            _, k := first.U.Dims()
+            if mc.r > min(mc.d, k + mc.c2) {
+                continue
+            }
+
+            U, Sigma, err := Merge(first.U, first.Sigma, U2, Sigma2, mc.r, weights)
+            if err != nil {
+                t.Fatalf("%s: Merge: %v", name, err)
+            }
+
+            var W1, W2 mat.Dense
+            W1.Mul(first.U, first.Sigma)
+            W1.Scale(weights.HistoryWeight, &W1)
+            W2.Scale(weights.NewDataWeight, X2)
+            refU, refSigma, err := ConcatSVD(&W1, &W2, mc.r)
+            if err != nil {
+                t.Fatalf("%s: ConcatSVD: %v", name, err)
+            }
+            compareFactorisations(t, name, U, Sigma, refU, refSigma, concatValues(t, &W1, &W2))
+        }
+    }
+}
+
+/* Merges of two d x r estimates at rank r, as the FPCA agent and aggregator do */
+var benchmarkDims = []int{2, 8, 64, 256, 512}
+
+func benchmarkMerge(b *testing.B, merge func(X1, X2 *mat.Dense, r int) (*mat.Dense, *mat.DiagDense, error)) {
+    for _, d := range benchmarkDims {
+        r := min(d, 4)
+        rng := rand.New(rand.NewSource(int64(d)))
+        X1, X2 := randomDense(rng, d, r), randomDense(rng, d, r)
+        b.Run(fmt.Sprintf("d=%d", d), func(b *testing.B) {
+            for range b.N {
+                if _, _, err := merge(X1, X2, r); err != nil {
+                    b.Fatal(err)
+                }
+            }
+        })
+    }
+}
+
+func BenchmarkAggMerge(b *testing.B) {
+    benchmarkMerge(b, AggMerge)
+}
+
+func BenchmarkConcatSVD(b *testing.B) {
+    benchmarkMerge(b, ConcatSVD)
+}
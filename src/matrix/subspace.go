@@ -0,0 +1,257 @@
+package matrix
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+Flips the sign of each column of U, in place, so that its largest-magnitude
+entry is positive, the first such entry on ties. Singular vectors are only
+defined up to sign, so without this the same subspace can come back from
+SVDR with different columns. Zero columns are left as they are
+*/
+func canonicaliseSigns(U *mat.Dense) {
+    ur, uc := U.Dims()
+    for j := range uc {
+        var largest float64
+        for i := range ur {
+            if v := U.At(i, j); math.Abs(v) > math.Abs(largest) {
+                largest = v
+            }
+        }
+        if largest >= 0 {
+            continue
+        }
+        for i := range ur {
+            U.Set(i, j, -U.At(i, j))
+        }
+    }
+}
+
+/*
+Principal angles in radians between span(U1) and span(U2), smallest first.
+Both must have the same number of rows and there are as many angles as the
+smaller of their column counts. Columns that are zero or dependent on earlier
+ones, as in an estimate that has seen little or no data, add nothing to the
+span: the angles missing for them are pi/2
+*/
+func PrincipalAngles(U1, U2 mat.Matrix) ([]float64, error) {
+    angles, _, _, err := principalAngles("PrincipalAngles", U1, U2)
+    if err != nil {
+        return nil, err
+    }
+    _, c1 := U1.Dims()
+    _, c2 := U2.Dims()
+    for len(angles) < min(c1, c2) {
+        angles = append(angles, math.Pi / 2)
+    }
+    return angles, nil
+}
+
+/*
+Angles between the spans of U1 and U2 along with the ranks k1 and k2 of
+those spans, one angle for each of the smaller rank. Both inputs are
+orthonormalised first, so the cosines of the angles are the singular values
+of Q1^T Q2 and, with Q1 the larger basis, the sines are those of
+Q2 - Q1 Q1^T Q2. Taking the angle from both keeps small angles accurate,
+where the arccosine alone is not
+*/
+func principalAngles(name string, U1, U2 mat.Matrix) ([]float64, int, int, error) {
+    if U1 == nil || U2 == nil {
+        return nil, 0, 0, ErrNilMatrix
+    }
+    r1, _ := U1.Dims()
+    r2, _ := U2.Dims()
+    if r1 != r2 {
+        return nil, 0, 0, dimensionError(name, "U1 and U2 have %d and %d rows", r1, r2)
+    }
+    if err := checkFinite(name, U1); err != nil {
+        return nil, 0, 0, err
+    }
+    if err := checkFinite(name, U2); err != nil {
+        return nil, 0, 0, err
+    }
+
+    Q1, _ := orthogonalise(nil, U1)
+    Q2, _ := orthogonalise(nil, U2)
+    var k1, k2 int
+    if Q1 != nil {
+        _, k1 = Q1.Dims()
+    }
+    if Q2 != nil {
+        _, k2 = Q2.Dims()
+    }
+    if k1 == 0 || k2 == 0 {
+        return nil, k1, k2, nil
+    }
+    if k1 < k2 {
+        Q1, Q2 = Q2, Q1
+    }
+    p := min(k1, k2)
+
+    var cross mat.Dense
+    cross.Mul(Q1.T(), Q2)
+    var residual mat.Dense
+    residual.Mul(Q1, &cross)
+    residual.Sub(Q2, &residual)
+
+    var svd mat.SVD
+    if !svd.Factorize(&cross, mat.SVDNone) {
+        return nil, 0, 0, ErrNoConvergence
+    }
+    cosines := svd.Values(nil)
+    if !svd.Factorize(&residual, mat.SVDNone) {
+        return nil, 0, 0, ErrNoConvergence
+    }
+    sines := svd.Values(nil)
+
+    /* Cosines and sines are both in descending order */
+    angles := make([]float64, p)
+    for i := range angles {
+        angles[i] = math.Atan2(sines[p-1-i], cosines[i])
+    }
+    return angles, k1, k2, nil
+}
+
+/*
+Chordal distance between span(U1) and span(U2), ||P1 - P2||_F / sqrt(2) for
+the orthogonal projections P onto each span. With k1 and k2 the ranks this
+is sqrt((k1 + k2) / 2 - sum cos^2 theta_i), which is sqrt(sum sin^2 theta_i)
+when the ranks match. As with PrincipalAngles the ranks do not count zero or
+dependent columns
+*/
+func ChordalDistance(U1, U2 mat.Matrix) (float64, error) {
+    angles, k1, k2, err := principalAngles("ChordalDistance", U1, U2)
+    if err != nil {
+        return 0, err
+    }
+
+    squared := float64(k1 + k2) / 2
+    for _, theta := range angles {
+        squared -= math.Pow(math.Cos(theta), 2)
+    }
+    return math.Sqrt(max(squared, 0)), nil
+}
+
+/*
+Projection distance between span(U1) and span(U2), ||P1 - P2||_2: the sine of
+the largest principal angle, or 1 if the ranks differ. As with
+PrincipalAngles the ranks do not count zero or dependent columns
+*/
+func ProjectionDistance(U1, U2 mat.Matrix) (float64, error) {
+    angles, k1, k2, err := principalAngles("ProjectionDistance", U1, U2)
+    if err != nil {
+        return 0, err
+    }
+    if k1 != k2 {
+        return 1, nil
+    }
+    if len(angles) == 0 {
+        return 0, nil
+    }
+    return math.Sin(angles[len(angles) - 1]), nil
+}
+
+/*
+Fraction of the energy of USigma, ||USigma||_F^2, that lies in span(U), i.e.
+||U^T USigma||_F^2 / ||USigma||_F^2. U must have orthonormal columns. A zero
+USigma has nothing left unexplained and gives 1
+*/
+func ExplainedVarianceRatio(U, USigma mat.Matrix) (float64, error) {
+    if U == nil || USigma == nil {
+        return 0, ErrNilMatrix
+    }
+    ur, _ := U.Dims()
+    xr, _ := USigma.Dims()
+    if ur != xr {
+        return 0, dimensionError("ExplainedVarianceRatio", "U and USigma have %d and %d rows", ur, xr)
+    }
+    if err := checkFinite("ExplainedVarianceRatio", U); err != nil {
+        return 0, err
+    }
+    if err := checkFinite("ExplainedVarianceRatio", USigma); err != nil {
+        return 0, err
+    }
+
+    total := math.Pow(mat.Norm(USigma, 2), 2)
+    if total == 0 {
+        return 1, nil
+    }
+    var projected mat.Dense
+    projected.Mul(U.T(), USigma)
+    return min(math.Pow(mat.Norm(&projected, 2), 2) / total, 1), nil
+}
+
+/* How far a subspace estimate moved from the previous one */
+type Drift struct {
+    /* Principal angles in radians, smallest first */
+    Angles            []float64
+    Chordal           float64
+    Projection        float64
+    /* Share of the new estimate's energy inside the previous span */
+    ExplainedVariance float64
+}
+
+/*
+Drift from the estimate prevUSigma to USigma, both U Sigma products as shared
+with the aggregator. Each span is taken from the columns that are not zero or
+linearly dependent, so a rank whose last singular values are zero counts as
+the smaller rank. A zero estimate spans nothing: moving from or to one is a
+projection distance of 1
+*/
+func SubspaceDrift(prevUSigma, USigma mat.Matrix) (Drift, error) {
+    if prevUSigma == nil || USigma == nil {
+        return Drift{}, ErrNilMatrix
+    }
+    pr, _ := prevUSigma.Dims()
+    ur, _ := USigma.Dims()
+    if pr != ur {
+        return Drift{}, dimensionError("SubspaceDrift", "estimates have %d and %d rows", pr, ur)
+    }
+    if err := checkFinite("SubspaceDrift", prevUSigma); err != nil {
+        return Drift{}, err
+    }
+    if err := checkFinite("SubspaceDrift", USigma); err != nil {
+        return Drift{}, err
+    }
+
+    prevU, _ := orthogonalise(nil, prevUSigma)
+    U, _ := orthogonalise(nil, USigma)
+    if prevU == nil || U == nil {
+        var k int
+        if U != nil {
+            _, k = U.Dims()
+        } else if prevU != nil {
+            _, k = prevU.Dims()
+        }
+        drift := Drift{
+            Chordal:           math.Sqrt(float64(k) / 2),
+            ExplainedVariance: 1,
+        }
+        if k > 0 {
+            drift.Projection = 1
+        }
+        if U != nil {
+            drift.ExplainedVariance = 0
+        }
+        return drift, nil
+    }
+
+    var drift Drift
+    var err error
+    if drift.Angles, err = PrincipalAngles(prevU, U); err != nil {
+        return Drift{}, err
+    }
+    if drift.Chordal, err = ChordalDistance(prevU, U); err != nil {
+        return Drift{}, err
+    }
+    if drift.Projection, err = ProjectionDistance(prevU, U); err != nil {
+        return Drift{}, err
+    }
+    if drift.ExplainedVariance, err = ExplainedVarianceRatio(prevU, USigma); err != nil {
+        return Drift{}, err
+    }
+    return drift, nil
+}
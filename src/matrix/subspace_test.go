@@ -0,0 +1,168 @@
+package matrix
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/* Absolute tolerance of angles and distances between known subspaces */
+const angleTolerance = 1e-12
+
+/* Columns of the identity of size d */
+func basisVectors(d int, cols ...int) *mat.Dense {
+    m := mat.NewDense(d, len(cols), nil)
+    for j, i := range cols {
+        m.Set(i, j, 1)
+    }
+    return m
+}
+
+func columns(d int, cols ...[]float64) *mat.Dense {
+    m := mat.NewDense(d, len(cols), nil)
+    for j, col := range cols {
+        m.SetCol(j, col)
+    }
+    return m
+}
+
+func closeTo(got, want []float64) bool {
+    if len(got) != len(want) {
+        return false
+    }
+    for i := range got {
+        if math.Abs(got[i] - want[i]) > angleTolerance {
+            return false
+        }
+    }
+    return true
+}
+
+func TestCanonicaliseSignsBothSigns(t *testing.T) {
+    rng := rand.New(rand.NewSource(3))
+    for _, dims := range [][2]int{{2, 1}, {5, 3}, {16, 4}, {40, 8}} {
+        d, r := dims[0], dims[1]
+        B := randomDense(rng, d, r + 2)
+        var negB mat.Dense
+        negB.Scale(-1, B)
+
+        U, _, err := SVDR(B, r)
+        if err != nil {
+            t.Fatal(err)
+        }
+        negU, _, err := SVDR(&negB, r)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !mat.EqualApprox(U, negU, 1e-9) {
+            t.Errorf("d=%d r=%d: U of B and -B differ by %v", d, r, maxAbsDiff(U, negU))
+        }
+    }
+}
+
+func TestCanonicaliseSigns(t *testing.T) {
+    for _, tc := range []struct {
+        name string
+        U    *mat.Dense
+        want *mat.Dense
+    }{
+        {"positive", columns(3, []float64{-0.6, 0.8, 0}), columns(3, []float64{-0.6, 0.8, 0})},
+        {"negative", columns(3, []float64{0.6, -0.8, 0}), columns(3, []float64{-0.6, 0.8, 0})},
+        {"tie takes the first", columns(2, []float64{-1, 1}), columns(2, []float64{1, -1})},
+        {"zero column", columns(2, []float64{0, 0}, []float64{0, -1}), columns(2, []float64{0, 0}, []float64{0, 1})},
+    } {
+        canonicaliseSigns(tc.U)
+        if !mat.Equal(tc.U, tc.want) {
+            t.Errorf("%s: got %v, want %v", tc.name, mat.Formatted(tc.U), mat.Formatted(tc.want))
+        }
+    }
+}
+
+/* Plane spanned by e1 and e2 rotated by theta about e1, towards e3 */
+func rotatedPlane(theta float64) *mat.Dense {
+    return columns(4, []float64{1, 0, 0, 0}, []float64{0, math.Cos(theta), math.Sin(theta), 0})
+}
+
+func TestPrincipalAngles(t *testing.T) {
+    s := 1 / math.Sqrt2
+    for _, tc := range []struct {
+        name   string
+        U1, U2 *mat.Dense
+        want   []float64
+    }{
+        {"identical", basisVectors(3, 0, 1), basisVectors(3, 0, 1), []float64{0, 0}},
+        {"swapped columns", basisVectors(3, 0, 1), basisVectors(3, 1, 0), []float64{0, 0}},
+        {"rotation", basisVectors(4, 0, 1), rotatedPlane(0.3), []float64{0, 0.3}},
+        {"small rotation", basisVectors(4, 0, 1), rotatedPlane(1e-7), []float64{0, 1e-7}},
+        {"orthogonal", basisVectors(4, 0, 1), basisVectors(4, 2, 3), []float64{math.Pi / 2, math.Pi / 2}},
+        {"ranks differ", basisVectors(3, 0, 1), columns(3, []float64{s, 0, s}), []float64{math.Pi / 4}},
+        {"ranks differ swapped", columns(3, []float64{s, 0, s}), basisVectors(3, 0, 1), []float64{math.Pi / 4}},
+        {"zero basis", basisVectors(3, 0, 1), mat.NewDense(3, 2, nil), []float64{math.Pi / 2, math.Pi / 2}},
+        {"both zero", mat.NewDense(3, 1, nil), mat.NewDense(3, 1, nil), []float64{math.Pi / 2}},
+        {"partly zero", basisVectors(3, 0, 1), columns(3, []float64{s, 0, s}, []float64{0, 0, 0}),
+            []float64{math.Pi / 4, math.Pi / 2}},
+        {"partly zero first", basisVectors(3, 0, 1), columns(3, []float64{0, 0, 0}, []float64{s, 0, s}),
+            []float64{math.Pi / 4, math.Pi / 2}},
+        {"dependent column", basisVectors(3, 0, 1), columns(3, []float64{0, 1, 0}, []float64{0, 1, 0}),
+            []float64{0, math.Pi / 2}},
+    } {
+        got, err := PrincipalAngles(tc.U1, tc.U2)
+        if err != nil {
+            t.Fatalf("%s: %v", tc.name, err)
+        }
+        if !closeTo(got, tc.want) {
+            t.Errorf("%s: angles %v, want %v", tc.name, got, tc.want)
+        }
+    }
+}
+
+func TestPrincipalAnglesErrors(t *testing.T) {
+    if _, err := PrincipalAngles(nil, basisVectors(2, 0)); err != ErrNilMatrix {
+        t.Errorf("nil U1: got %v, want %v", err, ErrNilMatrix)
+    }
+    if _, err := PrincipalAngles(basisVectors(2, 0), basisVectors(3, 0)); err == nil {
+        t.Error("mismatched rows: got no error")
+    }
+    nan := columns(2, []float64{math.NaN(), 0})
+    if _, err := PrincipalAngles(basisVectors(2, 0), nan); err == nil {
+        t.Error("NaN entry: got no error")
+    }
+}
+
+func TestDistances(t *testing.T) {
+    s := 1 / math.Sqrt2
+    for _, tc := range []struct {
+        name       string
+        U1, U2     *mat.Dense
+        chordal    float64
+        projection float64
+    }{
+        {"identical", basisVectors(3, 0, 1), basisVectors(3, 1, 0), 0, 0},
+        {"rotation", basisVectors(4, 0, 1), rotatedPlane(0.3), math.Sin(0.3), math.Sin(0.3)},
+        {"orthogonal", basisVectors(4, 0, 1), basisVectors(4, 2, 3), math.Sqrt(2), 1},
+        /* (2 + 1) / 2 - cos^2(pi/4) */
+        {"ranks differ", basisVectors(3, 0, 1), columns(3, []float64{s, 0, s}), 1, 1},
+        /* (2 + 1) / 2 - 1 */
+        {"nested", basisVectors(3, 0, 1), basisVectors(3, 0), math.Sqrt(0.5), 1},
+        {"partly zero", basisVectors(3, 0, 1), columns(3, []float64{s, 0, s}, []float64{0, 0, 0}), 1, 1},
+        {"zero basis", basisVectors(3, 0, 1), mat.NewDense(3, 2, nil), 1, 1},
+        {"both zero", mat.NewDense(3, 2, nil), mat.NewDense(3, 2, nil), 0, 0},
+    } {
+        chordal, err := ChordalDistance(tc.U1, tc.U2)
+        if err != nil {
+            t.Fatalf("%s: %v", tc.name, err)
+        }
+        if math.Abs(chordal - tc.chordal) > angleTolerance {
+            t.Errorf("%s: chordal distance %v, want %v", tc.name, chordal, tc.chordal)
+        }
+        projection, err := ProjectionDistance(tc.U1, tc.U2)
+        if err != nil {
+            t.Fatalf("%s: %v", tc.name, err)
+        }
+        if math.Abs(projection - tc.projection) > angleTolerance {
+            t.Errorf("%s: projection distance %v, want %v", tc.name, projection, tc.projection)
+        }
+    }
+}
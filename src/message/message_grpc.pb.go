@@ -33,14 +33,17 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PodPlacement_RequestPod_FullMethodName = "/message.PodPlacement/RequestPod"
+	PodPlacement_RequestPod_FullMethodName    = "/message.PodPlacement/RequestPod"
+	PodPlacement_StreamSignals_FullMethodName = "/message.PodPlacement/StreamSignals"
 )
 
 // PodPlacementClient is the client API for PodPlacement service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type PodPlacementClient interface {
+	// Kept for remote schedulers that do not support StreamSignals
 	RequestPod(ctx context.Context, in *PodRequest, opts ...grpc.CallOption) (*EmptyReply, error)
+	StreamSignals(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SignalReport, CentralMessage], error)
 }
 
 type podPlacementClient struct {
@@ -61,11 +64,26 @@ func (c *podPlacementClient) RequestPod(ctx context.Context, in *PodRequest, opt
 	return out, nil
 }
 
+func (c *podPlacementClient) StreamSignals(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SignalReport, CentralMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PodPlacement_ServiceDesc.Streams[0], PodPlacement_StreamSignals_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SignalReport, CentralMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PodPlacement_StreamSignalsClient = grpc.BidiStreamingClient[SignalReport, CentralMessage]
+
 // PodPlacementServer is the server API for PodPlacement service.
 // All implementations must embed UnimplementedPodPlacementServer
 // for forward compatibility.
 type PodPlacementServer interface {
+	// Kept for remote schedulers that do not support StreamSignals
 	RequestPod(context.Context, *PodRequest) (*EmptyReply, error)
+	StreamSignals(grpc.BidiStreamingServer[SignalReport, CentralMessage]) error
 	mustEmbedUnimplementedPodPlacementServer()
 }
 
@@ -79,6 +97,9 @@ type UnimplementedPodPlacementServer struct{}
 func (UnimplementedPodPlacementServer) RequestPod(context.Context, *PodRequest) (*EmptyReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RequestPod not implemented")
 }
+func (UnimplementedPodPlacementServer) StreamSignals(grpc.BidiStreamingServer[SignalReport, CentralMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamSignals not implemented")
+}
 func (UnimplementedPodPlacementServer) mustEmbedUnimplementedPodPlacementServer() {}
 func (UnimplementedPodPlacementServer) testEmbeddedByValue()                      {}
 
@@ -118,6 +139,13 @@ func _PodPlacement_RequestPod_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PodPlacement_StreamSignals_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PodPlacementServer).StreamSignals(&grpc.GenericServerStream[SignalReport, CentralMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PodPlacement_StreamSignalsServer = grpc.BidiStreamingServer[SignalReport, CentralMessage]
+
 // PodPlacement_ServiceDesc is the grpc.ServiceDesc for PodPlacement service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -130,7 +158,14 @@ var PodPlacement_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _PodPlacement_RequestPod_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSignals",
+			Handler:       _PodPlacement_StreamSignals_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "src/message/message.proto",
 }
 
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/mem"
+
+	"github.com/LucaChot/pronto/src/snapshot"
+)
+
+/* Parents of the pod cgroups under the systemd and cgroupfs drivers */
+var kubepodsDirs = []string{"kubepods.slice", "kubepods"}
+
+/*
+Matches pod cgroups, e.g. kubepods-burstable-pod<uid>.slice under systemd,
+where the UID's dashes are underscores, or pod<uid> under cgroupfs
+*/
+var podCgroup = regexp.MustCompile(`pod([0-9a-fA-F_-]{36})(\.slice)?$`)
+
+/* Cumulative counters of a pod cgroup */
+type podCounters struct {
+    cpuUsec uint64
+    memory  uint64
+    rbytes  uint64
+    wbytes  uint64
+}
+
+/*
+Per-pod usage, derived from the change in each pod cgroup's counters between
+consecutive samples
+*/
+type cgroupStats struct {
+    /* Config.CgroupRoot */
+    root     string
+    prevTime time.Time
+    prev     map[string]podCounters
+}
+
+/* Returns the directory holding the pod cgroups, or an error if there is none */
+func (cs *cgroupStats) kubepodsRoot() (string, error) {
+    if _, err := os.Stat(filepath.Join(cs.root, "cgroup.controllers")); err != nil {
+        return "", fmt.Errorf("%s is not a cgroup v2 hierarchy: %w", cs.root, err)
+    }
+    for _, dir := range kubepodsDirs {
+        path := filepath.Join(cs.root, dir)
+        if _, err := os.Stat(path); err == nil {
+            return path, nil
+        }
+    }
+    return "", fmt.Errorf("no kubepods cgroup under %s", cs.root)
+}
+
+/*
+Walks the kubepods hierarchy and returns the usage of every pod. Pods seen
+for the first time report no CPU or I/O until the next call
+*/
+func (cs *cgroupStats) collect() (*snapshot.PodSnapshot, error) {
+    root, err := cs.kubepodsRoot()
+    if err != nil {
+        return nil, err
+    }
+    cpus, err := cpu.Counts(true)
+    if err != nil {
+        return nil, fmt.Errorf("failed to count CPUs: %w", err)
+    }
+    vm, err := mem.VirtualMemory()
+    if err != nil {
+        return nil, fmt.Errorf("failed to read /proc/meminfo: %w", err)
+    }
+
+    counters := make(map[string]podCounters)
+    err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+        if err != nil || !d.IsDir() {
+            /* Pods can go away mid-walk */
+            return nil
+        }
+        m := podCgroup.FindStringSubmatch(d.Name())
+        if m == nil {
+            return nil
+        }
+        if pc, err := readPodCounters(path); err == nil {
+            counters[strings.ReplaceAll(m[1], "_", "-")] = pc
+        }
+        /* Container cgroups below the pod are already included in its counters */
+        return filepath.SkipDir
+    })
+    if err != nil {
+        return nil, err
+    }
+    now := time.Now()
+
+    elapsed := now.Sub(cs.prevTime).Seconds()
+    pods := &snapshot.PodSnapshot{
+        Time: now,
+        Pods: make(map[string]snapshot.PodUsage, len(counters)),
+    }
+    for uid, pc := range counters {
+        pu := snapshot.PodUsage{UID: uid}
+        if vm.Total > 0 {
+            pu.Memory = clamp(float64(pc.memory) / float64(vm.Total))
+        }
+        if prev, ok := cs.prev[uid]; ok && elapsed > 0 &&
+            pc.cpuUsec >= prev.cpuUsec && pc.rbytes >= prev.rbytes && pc.wbytes >= prev.wbytes {
+            pu.CPU = clamp(float64(pc.cpuUsec - prev.cpuUsec) / (elapsed * 1e6 * float64(cpus)))
+            pu.IORead = float64(pc.rbytes - prev.rbytes) / elapsed
+            pu.IOWrite = float64(pc.wbytes - prev.wbytes) / elapsed
+        }
+        pods.Pods[uid] = pu
+    }
+
+    cs.prevTime, cs.prev = now, counters
+    return pods, nil
+}
+
+func readPodCounters(path string) (podCounters, error) {
+    var pc podCounters
+
+    stat, err := os.ReadFile(filepath.Join(path, "cpu.stat"))
+    if err != nil {
+        return pc, err
+    }
+    pc.cpuUsec, _ = parseKeyed(stat)["usage_usec"]
+
+    current, err := os.ReadFile(filepath.Join(path, "memory.current"))
+    if err != nil {
+        return pc, err
+    }
+    pc.memory, err = strconv.ParseUint(strings.TrimSpace(string(current)), 10, 64)
+    if err != nil {
+        return pc, err
+    }
+
+    /* io.stat is absent when the io controller is not enabled for the pod */
+    if io, err := os.ReadFile(filepath.Join(path, "io.stat")); err == nil {
+        scanner := bufio.NewScanner(bytes.NewReader(io))
+        for scanner.Scan() {
+            fields := strings.Fields(scanner.Text())
+            if len(fields) < 2 {
+                continue
+            }
+            /* First field is the device's major:minor */
+            device := parseKeyed([]byte(strings.Join(fields[1:], "\n")))
+            pc.rbytes += device["rbytes"]
+            pc.wbytes += device["wbytes"]
+        }
+    }
+    return pc, nil
+}
+
+/* Parses "key value" lines, as in cpu.stat, or "key=value" fields, as in io.stat */
+func parseKeyed(raw []byte) map[string]uint64 {
+    values := make(map[string]uint64)
+    scanner := bufio.NewScanner(bytes.NewReader(raw))
+    for scanner.Scan() {
+        line := scanner.Text()
+        key, value, ok := strings.Cut(line, "=")
+        if !ok {
+            fields := strings.Fields(line)
+            if len(fields) != 2 {
+                continue
+            }
+            key, value = fields[0], fields[1]
+        }
+        if v, err := strconv.ParseUint(value, 10, 64); err == nil {
+            values[key] = v
+        }
+    }
+    return values
+}
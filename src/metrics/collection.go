@@ -1,41 +1,66 @@
 package metrics
 
 import (
-	log "github.com/sirupsen/logrus"
+	"fmt"
 
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/mem"
+
+	"github.com/LucaChot/pronto/src/schema"
 )
 
-func collectCPU() float64 {
-    stat, err := cpu.Percent(0, false)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"ERROR": err,
-		}).Fatal("FAILED TO READ /proc/stat")
-	}
-    return stat[0] / 100
+func init() {
+    Register([]string{schema.CPU}, func(Config) []Collector {
+        return []Collector{&funcCollector{name: schema.CPU, sample: collectCPU, lo: 0, hi: 100}}
+    })
+    Register([]string{schema.Memory}, func(Config) []Collector {
+        return []Collector{&funcCollector{name: schema.Memory, sample: collectRAM, lo: 0, hi: 100}}
+    })
 }
 
-func collectRAM() (float64) {
-    stat, err := mem.VirtualMemory()
+/* Percentage of CPU time spent busy since the last call */
+func collectCPU() (float64, error) {
+    stat, err := cpu.Percent(0, false)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"ERROR": err,
-		}).Fatal("FAILED TO READ /proc/meminfo")
+		return 0, fmt.Errorf("failed to read /proc/stat: %w", err)
 	}
-	return stat.UsedPercent / 100
+    return stat[0], nil
 }
 
 /*
-TODO: Look into whether I should collect ReadDisk() vs ReadDiskStat()
+CPU busy time derived from its own /proc/stat baseline. cpu.Percent keeps a
+single package-wide baseline, so a second caller in the same interval would
+measure the microseconds since the cpu collector's call
 */
-func collectMemory() {
-    /* TODO: Look into how to include Disk Statistics */
-    /* Potentially attach the node directories to the pods */
+type cpuStats struct {
+    prevBusy  float64
+    prevTotal float64
 }
 
-func collectNetwork() {
-    /* TODO: Look into how to include Net Statistics without knowing capacity*/
-    /* Look into number of packets dropped */
+/* Percentage of CPU time spent busy since the last call, 0 on the first */
+func (cs *cpuStats) collect() (float64, error) {
+    times, err := cpu.Times(false)
+    if err != nil {
+        return 0, fmt.Errorf("failed to read /proc/stat: %w", err)
+    }
+    total := times[0].Total()
+    busy := total - times[0].Idle - times[0].Iowait
+
+    first := cs.prevTotal == 0
+    dBusy, dTotal := busy - cs.prevBusy, total - cs.prevTotal
+    cs.prevBusy, cs.prevTotal = busy, total
+
+    if first || dTotal <= 0 {
+        return 0, nil
+    }
+    return min(max(100 * dBusy / dTotal, 0), 100), nil
+}
+
+/* Percentage of memory in use */
+func collectRAM() (float64, error) {
+    stat, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read /proc/meminfo: %w", err)
+	}
+	return stat.UsedPercent, nil
 }
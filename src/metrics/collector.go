@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+/*
+A source of one metric dimension. Sample is called once per sample interval
+and its value is normalised into [0, 1] using Bounds before it enters Y
+*/
+type Collector interface {
+    /* Dimension name, as used in the metric schema */
+    Name() string
+    Sample() (float64, error)
+    /* Values Sample returns for an idle and a saturated node */
+    Bounds() (float64, float64)
+}
+
+/*
+Creates the collectors of one source, configured by the collector's config.
+Collectors created by the same call may share state, e.g. the disk dimensions
+all come from one read of /proc/diskstats
+*/
+type Source func(config Config) []Collector
+
+type registration struct {
+    names  []string
+    source Source
+}
+
+var (
+    registryMu sync.RWMutex
+    registry   = make(map[string]*registration)
+)
+
+/*
+Registers a source of the named dimensions. Sources are normally registered
+from an init function; registering a dimension twice panics
+*/
+func Register(names []string, source Source) {
+    registryMu.Lock()
+    defer registryMu.Unlock()
+
+    reg := &registration{
+        names:  names,
+        source: source,
+    }
+    for _, name := range names {
+        if _, ok := registry[name]; ok {
+            panic(fmt.Sprintf("metrics: dimension %q registered twice", name))
+        }
+        registry[name] = reg
+    }
+}
+
+/* Names of every registered dimension */
+func Registered() []string {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+
+    names := make([]string, 0, len(registry))
+    for name := range registry {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+/*
+Creates a collector for each dimension of config's schema, calling every
+source that is needed once
+*/
+func newCollectors(config Config) ([]Collector, error) {
+    names := config.Schema.Dimensions
+
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+
+    created := make(map[*registration]map[string]Collector)
+    collectors := make([]Collector, len(names))
+    for i, name := range names {
+        reg, ok := registry[name]
+        if !ok {
+            return nil, fmt.Errorf("unknown metric dimension %q", name)
+        }
+        if _, ok := created[reg]; !ok {
+            byName := make(map[string]Collector)
+            for _, c := range reg.source(config) {
+                byName[c.Name()] = c
+            }
+            created[reg] = byName
+        }
+        c, ok := created[reg][name]
+        if !ok {
+            return nil, fmt.Errorf("source of %q did not create a collector for it", name)
+        }
+        collectors[i] = c
+    }
+    return collectors, nil
+}
+
+/* Collector backed by a function */
+type funcCollector struct {
+    name   string
+    sample func() (float64, error)
+    lo, hi float64
+}
+
+func (fc *funcCollector) Name() string               { return fc.name }
+func (fc *funcCollector) Sample() (float64, error)   { return fc.sample() }
+func (fc *funcCollector) Bounds() (float64, float64) { return fc.lo, fc.hi }
+
+/*
+Reads several dimensions at once and hands them out one at a time. A
+dimension asking for a value it has already taken triggers the next read, so
+the source is read once per sample interval whichever of its dimensions are in
+the schema
+*/
+type sharedSample struct {
+    read   func() ([]float64, error)
+    values []float64
+    err    error
+    taken  []bool
+    valid  bool
+}
+
+func newSharedSample(n int, read func() ([]float64, error)) *sharedSample {
+    return &sharedSample{
+        read:  read,
+        taken: make([]bool, n),
+    }
+}
+
+func (ss *sharedSample) take(i int) (float64, error) {
+    if !ss.valid || ss.taken[i] {
+        ss.values, ss.err = ss.read()
+        ss.valid = true
+        clear(ss.taken)
+    }
+    ss.taken[i] = true
+    if ss.err != nil {
+        return 0, ss.err
+    }
+    return ss.values[i], nil
+}
+
+/* Creates a collector taking value i of every read */
+func (ss *sharedSample) collector(i int, name string, lo, hi float64) Collector {
+    return &funcCollector{
+        name:   name,
+        sample: func() (float64, error) { return ss.take(i) },
+        lo:     lo,
+        hi:     hi,
+    }
+}
+
+/* Creates one collector per name, each taking its value from ss */
+func (ss *sharedSample) collectors(names []string, lo, hi float64) []Collector {
+    collectors := make([]Collector, len(names))
+    for i, name := range names {
+        collectors[i] = ss.collector(i, name, lo, hi)
+    }
+    return collectors
+}
+
+/* Maps a sample into [0, 1] using the collector's bounds */
+func normalise(c Collector, v float64) float64 {
+    lo, hi := c.Bounds()
+    if hi <= lo {
+        return clamp(v)
+    }
+    return clamp((v - lo) / (hi - lo))
+}
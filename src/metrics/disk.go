@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+
+	"github.com/LucaChot/pronto/src/schema"
+)
+
+func init() {
+    Register([]string{schema.DiskBusy, schema.DiskThroughput, schema.IOWait}, func(config Config) []Collector {
+        ds := &diskStats{maxThroughput: config.DiskMaxThroughput}
+        return newSharedSample(3, func() ([]float64, error) {
+            busy, throughput, wait, err := ds.collect()
+            return []float64{busy, throughput, wait}, err
+        }).collectors([]string{schema.DiskBusy, schema.DiskThroughput, schema.IOWait}, 0, 1)
+    })
+}
+
+/* Floor of the calibrated throughput so an idle disk does not read as busy */
+const minDiskThroughput = 1 << 20
+
+/*
+Disk utilisation, derived from the change in the kernel's counters between
+consecutive samples
+*/
+type diskStats struct {
+    /* Config.DiskMaxThroughput, 0 to calibrate against peak */
+    maxThroughput float64
+
+    prevTime     time.Time
+    prevIoTime   uint64
+    prevBytes    uint64
+    prevIowait   float64
+    prevCPUTotal float64
+    peak         float64
+}
+
+/* Returns whether a /proc/diskstats entry is a whole physical disk */
+func isPhysicalDisk(name string) bool {
+    if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+        return false
+    }
+    /* Partitions are not listed in /sys/block */
+    _, err := os.Stat("/sys/block/" + name)
+    return err == nil
+}
+
+/*
+Returns the fraction of time the busiest disk spent on I/O, the read and
+write throughput normalised by the maximum, and the fraction of CPU time spent
+waiting on I/O. All three are 0 on the first call and after the summed
+counters went backwards, e.g. when a disk was detached, which starts a new
+baseline
+*/
+func (ds *diskStats) collect() (float64, float64, float64, error) {
+    counters, err := disk.IOCounters()
+    if err != nil {
+        return 0, 0, 0, fmt.Errorf("failed to read /proc/diskstats: %w", err)
+    }
+    times, err := cpu.Times(false)
+    if err != nil {
+        return 0, 0, 0, fmt.Errorf("failed to read /proc/stat: %w", err)
+    }
+    now := time.Now()
+
+    /* The busiest disk bounds latency, throughput adds up across disks */
+    var ioTime, bytes uint64
+    for name, c := range counters {
+        if !isPhysicalDisk(name) {
+            continue
+        }
+        ioTime = max(ioTime, c.IoTime)
+        bytes += c.ReadBytes + c.WriteBytes
+    }
+    iowait := times[0].Iowait
+    cpuTotal := times[0].Total()
+
+    first := ds.prevTime.IsZero()
+    reset := ioTime < ds.prevIoTime || bytes < ds.prevBytes
+    elapsed := now.Sub(ds.prevTime)
+    dIowait := iowait - ds.prevIowait
+    dCPUTotal := cpuTotal - ds.prevCPUTotal
+    var dIoTime, dBytes float64
+    if !reset {
+        dIoTime = float64(ioTime - ds.prevIoTime)
+        dBytes = float64(bytes - ds.prevBytes)
+    }
+
+    ds.prevTime, ds.prevIoTime, ds.prevBytes = now, ioTime, bytes
+    ds.prevIowait, ds.prevCPUTotal = iowait, cpuTotal
+
+    /* Unsigned differences of counters that went backwards would wrap */
+    if first || reset || elapsed <= 0 {
+        return 0, 0, 0, nil
+    }
+
+    /* IoTime is in milliseconds */
+    busy := clamp(dIoTime / float64(elapsed.Milliseconds()))
+
+    rate := dBytes / elapsed.Seconds()
+    maxRate := ds.maxThroughput
+    if maxRate <= 0 {
+        ds.peak = max(ds.peak, rate, minDiskThroughput)
+        maxRate = ds.peak
+    }
+    throughput := clamp(rate / maxRate)
+
+    var wait float64
+    if dCPUTotal > 0 {
+        wait = clamp(dIowait / dCPUTotal)
+    }
+
+    return busy, throughput, wait, nil
+}
+
+func clamp(v float64) float64 {
+    return min(max(v, 0), 1)
+}
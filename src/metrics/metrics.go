@@ -1,32 +1,223 @@
 package metrics
 
 import (
+	"fmt"
 	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gonum.org/v1/gonum/mat"
-)
 
-const (
-    d = 2
-    b = 10
+	"github.com/LucaChot/pronto/src/schema"
+	"github.com/LucaChot/pronto/src/snapshot"
 )
 
+/* How the collector samples Y and groups samples into windows B */
+type Config struct {
+    Schema   schema.Schema
+    /* Time between consecutive samples of Y */
+    Interval time.Duration
+    /* Samples per window, the b columns of B */
+    Window   int
+    /*
+    Samples between consecutive windows. Equal to Window for disjoint windows;
+    a smaller hop overlaps them so FPCA sees a new B every Hop samples while
+    each B still smooths over Window samples
+    */
+    Hop      int
+
+    /* Trace file every sample of Y is written to, none if empty */
+    RecordPath  string
+    /* Trace file replayed in place of the live collectors, none if empty */
+    ReplayPath  string
+    /* Replay speed relative to the recording, 0 replays without pausing */
+    ReplaySpeed float64
+
+    /*
+    Combined read and write throughput of the node's disks in bytes per second
+    treated as fully utilised. 0 calibrates against the highest throughput
+    observed so far
+    */
+    DiskMaxThroughput float64
+    /*
+    Capacity in bytes per second of all the node's physical links together,
+    used for receive and for transmit alike. Replaces the sum of the link
+    speeds read from /sys/class/net when set, 0 uses that sum
+    */
+    NetworkCapacity   float64
+    /*
+    Interface counters and devices of the node's network namespace. The
+    defaults only see the host's interfaces when running in it, so a pod
+    outside it reads those of a process on the host, e.g. /proc/1/net/dev
+    from a mount of the host's /proc, and a mount of the host's /sys
+    */
+    NetDevPath        string
+    SysClassNetPath   string
+    /*
+    Root of the node's cgroup v2 hierarchy, read for per-pod usage. Containers
+    see their own cgroup namespace, so the remote scheduler mounts the host's
+    hierarchy elsewhere
+    */
+    CgroupRoot        string
+}
+
+func DefaultConfig() Config {
+    return Config{
+        Schema:   schema.Default,
+        Interval: time.Second,
+        Window:   10,
+        Hop:      10,
+        ReplaySpeed: 1,
+        NetDevPath:      "/proc/net/dev",
+        SysClassNetPath: "/sys/class/net",
+        CgroupRoot:      "/sys/fs/cgroup",
+    }
+}
+
+func (c Config) Validate() error {
+    if c.Interval <= 0 {
+        return fmt.Errorf("sample interval must be positive, got %v", c.Interval)
+    }
+    if c.Window <= 0 {
+        return fmt.Errorf("window must be positive, got %d", c.Window)
+    }
+    if c.Hop <= 0 || c.Hop > c.Window {
+        return fmt.Errorf("hop must be between 1 and the window %d, got %d", c.Window, c.Hop)
+    }
+    if c.DiskMaxThroughput < 0 {
+        return fmt.Errorf("disk max throughput must not be negative, got %v", c.DiskMaxThroughput)
+    }
+    if c.NetworkCapacity < 0 {
+        return fmt.Errorf("network capacity must not be negative, got %v", c.NetworkCapacity)
+    }
+    if c.ReplayPath != "" {
+        if c.ReplaySpeed < 0 {
+            return fmt.Errorf("replay speed must not be negative, got %v", c.ReplaySpeed)
+        }
+        /* The trace stands in for the collectors */
+        return nil
+    }
+    return Validate(c.Schema)
+}
+
+/* Returns an error if a dimension of s has no registered collector */
+func Validate(s schema.Schema) error {
+    registryMu.RLock()
+    defer registryMu.RUnlock()
+
+    for _, dim := range s.Dimensions {
+        if _, ok := registry[dim]; !ok {
+            return fmt.Errorf("unknown metric dimension %q", dim)
+        }
+    }
+    return nil
+}
+
+/*
+A collector along with its health. A degraded collector keeps being sampled
+and reports its last good value until it succeeds again
+*/
+type dimension struct {
+    collector Collector
+    last      float64
+    degraded  atomic.Bool
+}
+
+/* A window B of consecutive samples, the columns oldest first */
+type Window struct {
+    /* Counts windows from 1 */
+    ID         uint64
+    /* Sequence number of the newest sample in B */
+    LastSample uint64
+    /* When the newest sample was taken, its recorded time when replaying */
+    Time       time.Time
+    B          *mat.Dense
+}
+
 type MetricsCollector struct {
+    config  Config
+    schema  schema.Schema
+    d       int
+    dims    []*dimension
+
+    /* Ring of the last Window samples */
     ys       []float64
     Y       atomic.Pointer[mat.VecDense]
     entries int
-    output  chan *mat.Dense
+    output  chan *Window
+    windows uint64
+
+    /* Receives every sample along with its sequence number and pod usage */
+    snapshots *snapshot.Publisher
+
+    cgroup      cgroupStats
+    podsFailing bool
+
+    record *traceWriter
+    replay *traceReplay
 }
 
-/* Look at potentially parallelising the setup */
-func New() (*MetricsCollector, <-chan *mat.Dense) {
+/*
+Look at potentially parallelising the setup. Samples are published to
+snapshots as well as Y
+*/
+func New(config Config, snapshots *snapshot.Publisher) (*MetricsCollector, <-chan *Window) {
+    if err := config.Validate(); err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID METRICS CONFIG")
+    }
+
+    s := config.Schema
 	mc := MetricsCollector{
-        ys:  make([]float64, b * d),
-        output: make(chan *mat.Dense),
+        config: config,
+        schema: s,
+        d:      s.D(),
+        ys:  make([]float64, config.Window * s.D()),
+        output: make(chan *Window),
+        snapshots: snapshots,
+        cgroup: cgroupStats{root: config.CgroupRoot},
+    }
+
+    var collectors []Collector
+    var err error
+    if config.ReplayPath != "" {
+        mc.replay, err = loadTrace(config.ReplayPath, s, config.ReplaySpeed)
+        if err != nil {
+            log.WithFields(log.Fields{
+                "PATH":  config.ReplayPath,
+                "ERROR": err,
+            }).Fatal("INVALID METRIC TRACE")
+        }
+        collectors = mc.replay.collectors(s)
+        log.WithFields(log.Fields{
+            "PATH":    config.ReplayPath,
+            "SAMPLES": len(mc.replay.rows),
+            "SPEED":   config.ReplaySpeed,
+        }).Info("METRIC: REPLAYING TRACE")
+    } else {
+        collectors, err = newCollectors(config)
+        if err != nil {
+            log.WithFields(log.Fields{
+                "ERROR": err,
+            }).Fatal("INVALID METRIC SCHEMA")
+        }
     }
-    mc.Y.Store(mat.NewVecDense(d, nil))
+
+    if config.RecordPath != "" {
+        mc.record, err = newTraceWriter(config.RecordPath, s)
+        if err != nil {
+            log.WithFields(log.Fields{
+                "PATH":  config.RecordPath,
+                "ERROR": err,
+            }).Fatal("FAILED TO CREATE METRIC TRACE")
+        }
+    }
+    for _, c := range collectors {
+        mc.dims = append(mc.dims, &dimension{collector: c})
+    }
+    mc.Y.Store(mat.NewVecDense(mc.d, nil))
+    mc.snapshots.PublishSample(0, time.Time{}, mc.Y.Load(), nil)
 
 
     go mc.Collect()
@@ -34,34 +225,148 @@ func New() (*MetricsCollector, <-chan *mat.Dense) {
 	return &mc, mc.output
 }
 
+/* Samples every dimension of the schema, in schema order */
+func (mc *MetricsCollector) sample() []float64 {
+    y := make([]float64, mc.d)
+    for i, dim := range mc.dims {
+        v, err := dim.collector.Sample()
+        if err != nil {
+            if !dim.degraded.Swap(true) {
+                log.WithFields(log.Fields{
+                    "DIMENSION": dim.collector.Name(),
+                    "ERROR":     err,
+                }).Warn("METRIC: COLLECTOR DEGRADED")
+            }
+            y[i] = dim.last
+            continue
+        }
+        if dim.degraded.Swap(false) {
+            log.WithFields(log.Fields{
+                "DIMENSION": dim.collector.Name(),
+            }).Info("METRIC: COLLECTOR RECOVERED")
+        }
+        dim.last = normalise(dim.collector, v)
+        y[i] = dim.last
+    }
+    return y
+}
+
+/*
+Reads per-pod usage from the kubepods cgroups. Nodes without cgroup v2 keep
+working with no pod usage
+*/
+func (mc *MetricsCollector) samplePods() *snapshot.PodSnapshot {
+    pods, err := mc.cgroup.collect()
+    if err != nil {
+        if !mc.podsFailing {
+            log.WithFields(log.Fields{
+                "ERROR": err,
+            }).Warn("METRIC: NO PER-POD ACCOUNTING")
+        }
+        mc.podsFailing = true
+        return nil
+    }
+    mc.podsFailing = false
+
+    total := pods.Total()
+    log.WithFields(log.Fields{
+        "PODS":   len(pods.Pods),
+        "CPU":    total.CPU,
+        "MEMORY": total.Memory,
+    }).Debug("METRIC: SAMPLED PODS")
+    return pods
+}
+
+/* Appends y to the trace, if recording. A failed write stops the recording */
+func (mc *MetricsCollector) recordSample(y []float64) {
+    if mc.record == nil {
+        return
+    }
+    if err := mc.record.write(time.Now(), y); err != nil {
+        log.WithFields(log.Fields{
+            "PATH":  mc.config.RecordPath,
+            "ERROR": err,
+        }).Warn("METRIC: STOPPED RECORDING TRACE")
+        mc.record.close()
+        mc.record = nil
+    }
+}
+
+/* Names of the dimensions whose collector failed its last sample */
+func (mc *MetricsCollector) Degraded() []string {
+    var names []string
+    for _, dim := range mc.dims {
+        if dim.degraded.Load() {
+            names = append(names, dim.collector.Name())
+        }
+    }
+    return names
+}
+
 func (mc *MetricsCollector) Collect() {
-    ticker := time.NewTicker(time.Second)
+    ticker := time.NewTicker(mc.config.Interval)
     defer ticker.Stop()
+
+    /* Number of samples taken, the ring holds the last min(taken, Window) */
+    taken := 0
     for {
-        for i := range b {
+        if mc.replay != nil {
+            if !mc.replay.wait() {
+                log.Info("METRIC: TRACE REPLAY FINISHED")
+                return
+            }
+        } else {
             <-ticker.C
-            row := d * i
-            cpu := collectCPU()
-            mem := collectRAM()
+        }
+        row := mc.d * (taken % mc.config.Window)
+        taken++
 
-            mc.ys[row] = cpu
-            mc.ys[row + 1] = mem
+        y := mc.sample()
+        copy(mc.ys[row:row + mc.d], y)
+        sampledAt := time.Now()
+        if mc.replay != nil {
+            sampledAt = mc.replay.time()
+        }
 
-            mc.Y.Store(mat.NewVecDense(d, []float64{cpu, mem}))
-            log.WithFields(log.Fields{
-                "CPU" : cpu,
-                "MEM" : mem,
-            }).Debug("METRIC: SENT Y")
+        /* Traces do not record pod usage */
+        var pods *snapshot.PodSnapshot
+        if mc.replay == nil {
+            pods = mc.samplePods()
         }
-        bT := mat.NewDense(b, d, mc.ys)
 
+        mc.Y.Store(mat.NewVecDense(mc.d, y))
+        mc.snapshots.PublishSample(uint64(taken), sampledAt, mc.Y.Load(), pods)
+        mc.recordSample(y)
 
-        var B mat.Dense
-        B.CloneFrom(bT.T())
+        fields := make(log.Fields, mc.d)
+        for j, dim := range mc.schema.Dimensions {
+            fields[dim] = y[j]
+        }
+        log.WithFields(fields).Debug("METRIC: SENT Y")
 
-        mc.output<- &B
-        log.Debug("METRIC: SENT B")
+        if taken < mc.config.Window || (taken - mc.config.Window) % mc.config.Hop != 0 {
+            continue
+        }
 
+        mc.windows++
+        mc.output<- &Window{
+            ID:         mc.windows,
+            LastSample: uint64(taken),
+            Time:       sampledAt,
+            B:          mc.window(taken),
+        }
+        log.WithFields(log.Fields{
+            "WINDOW": mc.windows,
+        }).Debug("METRIC: SENT B")
     }
 }
 
+/* Copies the ring into a d x Window matrix, oldest sample first */
+func (mc *MetricsCollector) window(taken int) *mat.Dense {
+    B := mat.NewDense(mc.d, mc.config.Window, nil)
+    for j := range mc.config.Window {
+        k := (taken + j) % mc.config.Window
+        B.SetCol(j, mc.ys[k * mc.d:(k + 1) * mc.d])
+    }
+    return B
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LucaChot/pronto/src/schema"
+)
+
+func init() {
+    Register([]string{schema.NetRecv, schema.NetSent, schema.NetFaults}, func(config Config) []Collector {
+        ns := &networkStats{
+            capacity:    config.NetworkCapacity,
+            netDev:      config.NetDevPath,
+            sysClassNet: config.SysClassNetPath,
+        }
+        return newSharedSample(3, func() ([]float64, error) {
+            recv, sent, faults, err := ns.collect()
+            return []float64{recv, sent, faults}, err
+        }).collectors([]string{schema.NetRecv, schema.NetSent, schema.NetFaults}, 0, 1)
+    })
+}
+
+const (
+    /* Floor of the observed-peak estimate so an idle link does not read as busy */
+    minNetworkThroughput = 1 << 20
+)
+
+/*
+Network utilisation, derived from the change in the interface counters between
+consecutive samples
+*/
+type networkStats struct {
+    /* Config.NetworkCapacity, 0 to sum the link speeds */
+    capacity    float64
+    /* Config.NetDevPath and Config.SysClassNetPath */
+    netDev      string
+    sysClassNet string
+    /* Whether the last sample found no physical interface */
+    noInterface bool
+
+    prevTime    time.Time
+    prevRecv    uint64
+    prevSent    uint64
+    prevPackets uint64
+    prevFaults  uint64
+
+    /* Observed peaks, used when no capacity is known */
+    peakRecv float64
+    peakSent float64
+}
+
+/* Returns whether an interface is backed by a device, skipping lo, veths and bridges */
+func (ns *networkStats) isPhysicalInterface(name string) bool {
+    _, err := os.Stat(filepath.Join(ns.sysClassNet, name, "device"))
+    return err == nil
+}
+
+/*
+Returns the link speed of an interface in bytes per second, or 0 if the
+kernel does not report one
+*/
+func (ns *networkStats) interfaceSpeed(name string) float64 {
+    raw, err := os.ReadFile(filepath.Join(ns.sysClassNet, name, "speed"))
+    if err != nil {
+        return 0
+    }
+    mbits, err := strconv.ParseFloat(strings.TrimSpace(string(raw)), 64)
+    if err != nil || mbits <= 0 {
+        return 0
+    }
+    return mbits * 1e6 / 8
+}
+
+/*
+Returns the receive and transmit utilisation of the node's physical links and
+the fraction of packets that were dropped or errored. All three are 0 on the
+first call and after the summed counters went backwards, e.g. when an
+interface was removed, which starts a new baseline
+*/
+func (ns *networkStats) collect() (float64, float64, float64, error) {
+    counters, err := net.IOCountersByFile(true, ns.netDev)
+    if err != nil {
+        return 0, 0, 0, fmt.Errorf("failed to read %s: %w", ns.netDev, err)
+    }
+    now := time.Now()
+
+    var recv, sent, packets, faults uint64
+    var capacity float64
+    var physical int
+    for _, c := range counters {
+        if !ns.isPhysicalInterface(c.Name) {
+            continue
+        }
+        recv += c.BytesRecv
+        sent += c.BytesSent
+        packets += c.PacketsRecv + c.PacketsSent
+        faults += c.Dropin + c.Dropout + c.Errin + c.Errout
+        capacity += ns.interfaceSpeed(c.Name)
+        physical++
+    }
+    /*
+    Inside a pod's network namespace there are only veths, so every dimension
+    would silently read 0
+    */
+    if physical == 0 && !ns.noInterface {
+        log.WithFields(log.Fields{
+            "NET DEV":       ns.netDev,
+            "SYS CLASS NET": ns.sysClassNet,
+            "INTERFACES":    len(counters),
+        }).Warn("METRIC: NO PHYSICAL NETWORK INTERFACE")
+    }
+    ns.noInterface = physical == 0
+    if ns.capacity > 0 {
+        capacity = ns.capacity
+    }
+
+    first := ns.prevTime.IsZero()
+    reset := recv < ns.prevRecv || sent < ns.prevSent ||
+        packets < ns.prevPackets || faults < ns.prevFaults
+    elapsed := now.Sub(ns.prevTime).Seconds()
+    var dRecv, dSent, dPackets, dFaults float64
+    if !reset {
+        dRecv = float64(recv - ns.prevRecv)
+        dSent = float64(sent - ns.prevSent)
+        dPackets = float64(packets - ns.prevPackets)
+        dFaults = float64(faults - ns.prevFaults)
+    }
+
+    ns.prevTime, ns.prevRecv, ns.prevSent = now, recv, sent
+    ns.prevPackets, ns.prevFaults = packets, faults
+
+    /* Unsigned differences of counters that went backwards would wrap */
+    if first || reset || elapsed <= 0 {
+        return 0, 0, 0, nil
+    }
+
+    recvRate := dRecv / elapsed
+    sentRate := dSent / elapsed
+
+    recvCapacity, sentCapacity := capacity, capacity
+    if capacity <= 0 {
+        ns.peakRecv = max(ns.peakRecv, recvRate, minNetworkThroughput)
+        ns.peakSent = max(ns.peakSent, sentRate, minNetworkThroughput)
+        recvCapacity, sentCapacity = ns.peakRecv, ns.peakSent
+    }
+
+    var faultRate float64
+    if dPackets > 0 {
+        faultRate = clamp(dFaults / dPackets)
+    }
+
+    return clamp(recvRate / recvCapacity), clamp(sentRate / sentCapacity), faultRate, nil
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LucaChot/pronto/src/schema"
+)
+
+const procPressure = "/proc/pressure"
+
+func init() {
+    registerPSI("cpu",
+        []string{schema.PSICPUSome, schema.PSICPUSomeTotal, schema.PSICPUFull, schema.PSICPUFullTotal},
+        func(Config) func() (float64, error) { return (&cpuStats{}).collect }, 100)
+    registerPSI("memory",
+        []string{schema.PSIMemorySome, schema.PSIMemorySomeTotal, schema.PSIMemoryFull, schema.PSIMemoryFullTotal},
+        func(Config) func() (float64, error) { return collectRAM }, 100)
+    registerPSI("io",
+        []string{schema.PSIIOSome, schema.PSIIOSomeTotal, schema.PSIIOFull, schema.PSIIOFullTotal},
+        func(config Config) func() (float64, error) {
+            ds := &diskStats{maxThroughput: config.DiskMaxThroughput}
+            return func() (float64, error) {
+                _, _, wait, err := ds.collect()
+                return wait, err
+            }
+        }, 1)
+}
+
+/*
+Registers the four PSI dimensions of a resource: some avg10, some total, full
+avg10 and full total. On kernels without PSI, or with it disabled, all four
+report the resource's existing collector instead, normalised by [0, hi], so
+the schema keeps its shape
+*/
+func registerPSI(resource string, names []string, fallback func(Config) func() (float64, error), hi float64) {
+    Register(names, func(config Config) []Collector {
+        ps := &psiStats{path: filepath.Join(procPressure, resource)}
+        if _, err := ps.collect(); err != nil {
+            log.WithFields(log.Fields{
+                "RESOURCE": resource,
+                "ERROR":    err,
+            }).Info("METRIC: PSI UNAVAILABLE, FALLING BACK")
+
+            sample := fallback(config)
+            return newSharedSample(len(names), func() ([]float64, error) {
+                v, err := sample()
+                return []float64{v, v, v, v}, err
+            }).collectors(names, 0, hi)
+        }
+
+        ss := newSharedSample(len(names), ps.collect)
+        return []Collector{
+            ss.collector(0, names[0], 0, 100),
+            ss.collector(1, names[1], 0, 1),
+            ss.collector(2, names[2], 0, 100),
+            ss.collector(3, names[3], 0, 1),
+        }
+    })
+}
+
+/* One line of a /proc/pressure file */
+type psiLine struct {
+    avg10 float64
+    /* Microseconds stalled since boot */
+    total uint64
+}
+
+/* Stall information of one resource, derived from /proc/pressure/<resource> */
+type psiStats struct {
+    path      string
+    prevTime  time.Time
+    prevSome  uint64
+    prevFull  uint64
+}
+
+/*
+Returns the some avg10, the fraction of time since the last call some tasks
+were stalled, and the same two for full. The fractions are 0 on the first
+call and after a total went backwards, which starts a new baseline. The cpu
+file of kernels before 5.13 has no full line, which reads as 0
+*/
+func (ps *psiStats) collect() ([]float64, error) {
+    raw, err := os.ReadFile(ps.path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read %s: %w", ps.path, err)
+    }
+    now := time.Now()
+
+    lines, err := parsePSI(raw)
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse %s: %w", ps.path, err)
+    }
+    some, ok := lines["some"]
+    if !ok {
+        return nil, fmt.Errorf("%s has no some line", ps.path)
+    }
+    full := lines["full"]
+
+    first := ps.prevTime.IsZero()
+    reset := some.total < ps.prevSome || full.total < ps.prevFull
+    elapsed := float64(now.Sub(ps.prevTime).Microseconds())
+    var dSome, dFull float64
+    if !reset {
+        dSome = float64(some.total - ps.prevSome)
+        dFull = float64(full.total - ps.prevFull)
+    }
+
+    ps.prevTime, ps.prevSome, ps.prevFull = now, some.total, full.total
+
+    /* Unsigned differences of totals that went backwards would wrap */
+    var someFrac, fullFrac float64
+    if !first && !reset && elapsed > 0 {
+        someFrac = clamp(dSome / elapsed)
+        fullFrac = clamp(dFull / elapsed)
+    }
+    return []float64{some.avg10, someFrac, full.avg10, fullFrac}, nil
+}
+
+/*
+Parses lines of the form
+some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+*/
+func parsePSI(raw []byte) (map[string]psiLine, error) {
+    lines := make(map[string]psiLine)
+    scanner := bufio.NewScanner(bytes.NewReader(raw))
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) == 0 {
+            continue
+        }
+
+        var line psiLine
+        for _, field := range fields[1:] {
+            key, value, ok := strings.Cut(field, "=")
+            if !ok {
+                return nil, fmt.Errorf("malformed field %q", field)
+            }
+            var err error
+            switch key {
+            case "avg10":
+                line.avg10, err = strconv.ParseFloat(value, 64)
+            case "total":
+                line.total, err = strconv.ParseUint(value, 10, 64)
+            }
+            if err != nil {
+                return nil, fmt.Errorf("malformed field %q: %w", field, err)
+            }
+        }
+        lines[fields[0]] = line
+    }
+    return lines, scanner.Err()
+}
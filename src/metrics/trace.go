@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/LucaChot/pronto/src/schema"
+)
+
+/*
+Traces are CSV files with a header of "timestamp" followed by the dimension
+names, then one row per sample of Y: the sample time in Unix nanoseconds and
+the normalised value of each dimension
+*/
+const traceTimestamp = "timestamp"
+
+/* Appends every sample of Y to a trace file */
+type traceWriter struct {
+    f *os.File
+    w *csv.Writer
+}
+
+func newTraceWriter(path string, s schema.Schema) (*traceWriter, error) {
+    f, err := os.Create(path)
+    if err != nil {
+        return nil, err
+    }
+    tw := &traceWriter{
+        f: f,
+        w: csv.NewWriter(f),
+    }
+    if err := tw.w.Write(append([]string{traceTimestamp}, s.Dimensions...)); err != nil {
+        f.Close()
+        return nil, err
+    }
+    return tw, nil
+}
+
+/* Writes one sample, flushing so that a crash loses at most the last row */
+func (tw *traceWriter) write(t time.Time, y []float64) error {
+    record := make([]string, 0, len(y) + 1)
+    record = append(record, strconv.FormatInt(t.UnixNano(), 10))
+    for _, v := range y {
+        record = append(record, strconv.FormatFloat(v, 'g', -1, 64))
+    }
+    if err := tw.w.Write(record); err != nil {
+        return err
+    }
+    tw.w.Flush()
+    return tw.w.Error()
+}
+
+func (tw *traceWriter) close() error {
+    tw.w.Flush()
+    return tw.f.Close()
+}
+
+type traceRow struct {
+    t      time.Time
+    values []float64
+}
+
+/*
+Plays a recorded trace back in place of live collectors. Samples are released
+with the spacing they were recorded at, divided by speed; a speed of 0 plays
+them back as fast as FPCA consumes them
+*/
+type traceReplay struct {
+    rows  []traceRow
+    /* Index of the current row, -1 before the first wait */
+    pos   int
+    speed float64
+}
+
+/* Reads a trace, keeping the columns of schema s in schema order */
+func loadTrace(path string, s schema.Schema, speed float64) (*traceReplay, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    r := csv.NewReader(f)
+    header, err := r.Read()
+    if err != nil {
+        return nil, fmt.Errorf("failed to read trace header: %w", err)
+    }
+    if len(header) == 0 || header[0] != traceTimestamp {
+        return nil, fmt.Errorf("trace header must start with %q", traceTimestamp)
+    }
+    columns := make(map[string]int, len(header))
+    for i, name := range header[1:] {
+        columns[name] = i + 1
+    }
+    index := make([]int, s.D())
+    for i, dim := range s.Dimensions {
+        col, ok := columns[dim]
+        if !ok {
+            return nil, fmt.Errorf("trace has no column for dimension %q", dim)
+        }
+        index[i] = col
+    }
+
+    tr := &traceReplay{
+        pos:   -1,
+        speed: speed,
+    }
+    for line := 2; ; line++ {
+        record, err := r.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("trace line %d: %w", line, err)
+        }
+        nanos, err := strconv.ParseInt(record[0], 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("trace line %d: %w", line, err)
+        }
+        row := traceRow{
+            t:      time.Unix(0, nanos),
+            values: make([]float64, s.D()),
+        }
+        for i, col := range index {
+            row.values[i], err = strconv.ParseFloat(record[col], 64)
+            if err != nil {
+                return nil, fmt.Errorf("trace line %d: %w", line, err)
+            }
+        }
+        tr.rows = append(tr.rows, row)
+    }
+    if len(tr.rows) == 0 {
+        return nil, fmt.Errorf("trace %s has no samples", path)
+    }
+    return tr, nil
+}
+
+/*
+Blocks until the next sample is due and makes it current. Returns false once
+the trace is exhausted
+*/
+func (tr *traceReplay) wait() bool {
+    if tr.pos + 1 >= len(tr.rows) {
+        return false
+    }
+    tr.pos++
+    if tr.pos > 0 && tr.speed > 0 {
+        gap := tr.rows[tr.pos].t.Sub(tr.rows[tr.pos - 1].t)
+        time.Sleep(time.Duration(float64(gap) / tr.speed))
+    }
+    return true
+}
+
+func (tr *traceReplay) current() ([]float64, error) {
+    if tr.pos < 0 {
+        return nil, fmt.Errorf("trace replay has not started")
+    }
+    return tr.rows[tr.pos].values, nil
+}
+
+/* Time the current row was recorded at */
+func (tr *traceReplay) time() time.Time {
+    if tr.pos < 0 {
+        return time.Time{}
+    }
+    return tr.rows[tr.pos].t
+}
+
+/*
+Creates one collector per dimension of s, reporting the current row of the
+trace. Traces hold values that were already normalised
+*/
+func (tr *traceReplay) collectors(s schema.Schema) []Collector {
+    return newSharedSample(s.D(), tr.current).collectors(s.Dimensions, 0, 1)
+}
@@ -2,37 +2,104 @@ package remote
 
 import (
 	"net"
+	"sync/atomic"
 	"time"
 
 	pb "github.com/LucaChot/pronto/src/message"
+	"github.com/LucaChot/pronto/src/snapshot"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
+	"gonum.org/v1/gonum/mat"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+const (
+    ctlService       = "central-svc.basic-sched.svc.cluster.local"
+    /* How often the replicas behind the headless service are looked up again */
+    ctlRefreshPeriod = 30 * time.Second
+)
+
+/*
+Connection to one central scheduler replica. Signals are sent to every replica
+so that standbys hold warm signal tables when they take over leadership
+*/
+type centralConn struct {
+    addr string
+    conn *grpc.ClientConn
+    stub pb.PodPlacementClient
+
+    stream            pb.PodPlacement_StreamSignalsClient
+    streamUnsupported atomic.Bool
+}
+
 func (rmt *RemoteScheduler) AsClient() {
-	ctrlAddr := findCtlAddr()
-	rmt.connectToPl(ctrlAddr)
+    rmt.centrals = make(map[string]*centralConn)
+	ctrlAddrs := findCtlAddrs()
+    rmt.connectToCentrals(ctrlAddrs)
+    rmt.lastRefresh = time.Now()
 }
 
-func findCtlAddr() net.IP {
+func findCtlAddrs() []net.IP {
 	for {
-		ips, err := net.LookupIP("central-svc.basic-sched.svc.cluster.local")
-		if err != nil {
+		ips, err := net.LookupIP(ctlService)
+		if err != nil || len(ips) == 0 {
 			log.WithFields(log.Fields{
 				"error": err,
 			}).Debug("Could not get IPs")
 			time.Sleep(time.Second * 5)
 		} else {
-			return ips[0]
+			return ips
 		}
 	}
 }
 
-func (rmt *RemoteScheduler) connectToPl(ctlAddr net.IP) {
+/* Connects to replicas not yet known and drops those that have gone away */
+func (rmt *RemoteScheduler) connectToCentrals(ctlAddrs []net.IP) {
+    current := make(map[string]bool, len(ctlAddrs))
+    for _, ip := range ctlAddrs {
+        addr := ip.String()
+        current[addr] = true
+        if _, ok := rmt.centrals[addr]; ok {
+            continue
+        }
+        rmt.centrals[addr] = connectToPl(addr)
+    }
+
+    for addr, c := range rmt.centrals {
+        if current[addr] {
+            continue
+        }
+        c.conn.Close()
+        delete(rmt.centrals, addr)
+        log.WithFields(log.Fields{
+            "ADDRESS": addr,
+        }).Debug("RMT: DROPPED CENTRAL REPLICA")
+    }
+}
+
+/* Looks up the central replicas again if the refresh period has passed */
+func (rmt *RemoteScheduler) refreshCentrals() {
+    if time.Since(rmt.lastRefresh) < ctlRefreshPeriod {
+        return
+    }
+    rmt.lastRefresh = time.Now()
 
-    conn, err := grpc.NewClient(ctlAddr.String()+":50051",
+    ips, err := net.LookupIP(ctlService)
+    if err != nil || len(ips) == 0 {
+        log.WithFields(log.Fields{
+            "error": err,
+        }).Debug("RMT: FAILED TO REFRESH CENTRAL REPLICAS")
+        return
+    }
+    rmt.connectToCentrals(ips)
+}
+
+func connectToPl(ctlAddr string) *centralConn {
+
+    conn, err := grpc.NewClient(ctlAddr+":50051",
         grpc.WithTransportCredentials(insecure.NewCredentials()))
 
 	if err != nil {
@@ -42,18 +109,158 @@ func (rmt *RemoteScheduler) connectToPl(ctlAddr net.IP) {
 		}).Fatal("Could not connect to controller")
 	}
 
-	rmt.ctlPlStub = pb.NewPodPlacementClient(conn)
+    log.WithFields(log.Fields{
+        "ADDRESS": ctlAddr,
+    }).Debug("RMT: CONNECTED TO CENTRAL REPLICA")
 
+	return &centralConn{
+        addr: ctlAddr,
+        conn: conn,
+        stub: pb.NewPodPlacementClient(conn),
+    }
 }
 
-func (rmt *RemoteScheduler) RequestPod(signal float64) {
+func (rmt *RemoteScheduler) requestPod(c *centralConn, signal float64) {
     ctx := context.Background()
-    rmt.ctlPlStub.RequestPod(ctx, &pb.PodRequest{
-        Node:   rmt.onNode.Name,
-        Signal: signal,
+    _, err := c.stub.RequestPod(ctx, &pb.PodRequest{
+        Node:     rmt.onNode.Name,
+        Signal:   signal,
+        Sequence: rmt.sequence,
     })
+    if err != nil {
+        log.WithFields(log.Fields{
+            "ADDRESS": c.addr,
+            "ERROR":   err,
+        }).Debug("RMT: FAILED POD REQUEST")
+        return
+    }
     log.WithFields(log.Fields{
+        "ADDRESS":  c.addr,
         "SIGNAL":   signal,
+        "SEQUENCE": rmt.sequence,
         "NODE":     rmt.onNode.Name,
     }).Debug("RMT: SENT POD REQUEST")
 }
+
+/*
+Opens the StreamSignals stream to a central replica and starts handling the
+messages it pushes back
+*/
+func (rmt *RemoteScheduler) openSignalStream(c *centralConn) error {
+    stream, err := c.stub.StreamSignals(context.Background())
+    if err != nil {
+        return err
+    }
+    c.stream = stream
+
+    go rmt.receiveCentralMessages(c, stream)
+
+    log.WithFields(log.Fields{
+        "ADDRESS": c.addr,
+    }).Debug("RMT: OPENED SIGNAL STREAM")
+    return nil
+}
+
+func (rmt *RemoteScheduler) receiveCentralMessages(c *centralConn, stream pb.PodPlacement_StreamSignalsClient) {
+    for {
+        msg, err := stream.Recv()
+        if err != nil {
+            if status.Code(err) == codes.Unimplemented {
+                /* Central scheduler predates StreamSignals */
+                c.streamUnsupported.Store(true)
+            }
+            log.WithFields(log.Fields{
+                "ADDRESS": c.addr,
+                "ERROR":   err,
+            }).Debug("RMT: SIGNAL STREAM CLOSED")
+            return
+        }
+
+        switch m := msg.Message.(type) {
+        case *pb.CentralMessage_Ack:
+            if !m.Ack.Accepted {
+                log.WithFields(log.Fields{
+                    "ADDRESS":  c.addr,
+                    "SEQUENCE": m.Ack.Sequence,
+                    "REASON":   m.Ack.Reason,
+                }).Debug("RMT: SIGNAL REJECTED")
+            }
+        case *pb.CentralMessage_Placement:
+            log.WithFields(log.Fields{
+                "NAMESPACE": m.Placement.Namespace,
+                "POD":       m.Placement.Pod,
+            }).Debug("RMT: POD PLACED ON NODE")
+        case *pb.CentralMessage_Config:
+            if m.Config.Threshold != nil {
+                rmt.SetThreshold(*m.Config.Threshold)
+            }
+        }
+    }
+}
+
+/*
+Reports a signal, computed from snap, to every central replica over
+StreamSignals, falling back to the unary RequestPod for any replica whose
+stream cannot be used. The U·Sigma reported is the one the signal used
+*/
+func (rmt *RemoteScheduler) SendSignal(signal float64, snap *snapshot.Snapshot) {
+    rmt.refreshCentrals()
+
+    rmt.sequence++
+    report := &pb.SignalReport{
+        Node:      rmt.onNode.Name,
+        Signal:    signal,
+        Sequence:  rmt.sequence,
+        Timestamp: time.Now().UnixNano(),
+    }
+
+    var uSigma mat.Dense
+    uSigma.Mul(snap.U, snap.Sigma)
+    rows, cols := uSigma.Dims()
+    report.Rank = int64(cols)
+    report.USigma = &pb.DenseMatrix{
+        Rows: int64(rows),
+        Cols: int64(cols),
+        Data: uSigma.RawMatrix().Data,
+        SchemaVersion: rmt.schema.Version(),
+    }
+
+    for _, c := range rmt.centrals {
+        rmt.sendReport(c, report)
+    }
+}
+
+func (rmt *RemoteScheduler) sendReport(c *centralConn, report *pb.SignalReport) {
+    if c.streamUnsupported.Load() {
+        rmt.requestPod(c, report.Signal)
+        return
+    }
+
+    if c.stream == nil {
+        if err := rmt.openSignalStream(c); err != nil {
+            log.WithFields(log.Fields{
+                "ADDRESS": c.addr,
+                "ERROR":   err,
+            }).Debug("RMT: FAILED TO OPEN SIGNAL STREAM")
+            rmt.requestPod(c, report.Signal)
+            return
+        }
+    }
+
+    if err := c.stream.Send(report); err != nil {
+        log.WithFields(log.Fields{
+            "ADDRESS": c.addr,
+            "ERROR":   err,
+        }).Debug("RMT: SIGNAL STREAM BROKEN")
+        c.stream = nil
+        rmt.requestPod(c, report.Signal)
+        return
+    }
+
+    log.WithFields(log.Fields{
+        "ADDRESS":  c.addr,
+        "SIGNAL":   report.Signal,
+        "SEQUENCE": report.Sequence,
+        "NODE":     rmt.onNode.Name,
+    }).Debug("RMT: STREAMED SIGNAL")
+}
@@ -5,14 +5,16 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gonum.org/v1/gonum/mat"
 
 	"github.com/LucaChot/pronto/src/fpca"
-	pb "github.com/LucaChot/pronto/src/message"
 	"github.com/LucaChot/pronto/src/metrics"
+	"github.com/LucaChot/pronto/src/schema"
+	"github.com/LucaChot/pronto/src/snapshot"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -24,17 +26,55 @@ const (
     TR = 0.5
 )
 
+type Config struct {
+    Metrics        metrics.Config
+    FPCA           fpca.Config
+    /* Time between job signal calculations, each possibly reported to the centrals */
+    ReportInterval time.Duration
+}
+
+func DefaultConfig() Config {
+    return Config{
+        Metrics:        metrics.DefaultConfig(),
+        FPCA:           fpca.DefaultConfig(),
+        ReportInterval: time.Second,
+    }
+}
+
+/*
+Checks the settings that span the collector and FPCA; each validates its own
+config when created
+*/
+func (c Config) Validate() error {
+    if c.ReportInterval <= 0 {
+        return fmt.Errorf("report interval must be positive, got %v", c.ReportInterval)
+    }
+    /* The first estimate is an SVD of one window, which has Window columns */
+    if c.FPCA.Rank > c.Metrics.Window {
+        return fmt.Errorf("rank %d is larger than the window of %d samples", c.FPCA.Rank, c.Metrics.Window)
+    }
+    return nil
+}
+
 type RemoteScheduler struct {
+    config   Config
+    schema   schema.Schema
     hostname string
     onNode *v1.Node
 
     mc *metrics.MetricsCollector
     fp *fpca.FPCAAgent
+    /* Y, U and Sigma as one consistent snapshot */
+    snapshots *snapshot.Publisher
 
-    tr float64
+    /* Signal threshold, stored as float64 bits as the central may update it */
+    tr atomic.Uint64
 
     clientset   *kubernetes.Clientset
-    ctlPlStub  pb.PodPlacementClient
+    /* Central scheduler replicas, keyed by address */
+    centrals    map[string]*centralConn
+    lastRefresh time.Time
+    sequence    uint64
 }
 
 func (rmt *RemoteScheduler) SetClientset() {
@@ -81,21 +121,29 @@ func (rmt *RemoteScheduler) SetOnNode() {
     rmt.onNode = &n.Items[0]
 }
 
-/* Creates a new CentralScheduler */
-func New() *RemoteScheduler {
+func New(config Config) *RemoteScheduler {
+    if err := config.Validate(); err != nil {
+		log.WithFields(log.Fields{
+			"ERROR": err,
+		}).Fatal("INVALID REMOTE CONFIG")
+    }
+    s := config.Metrics.Schema
 
     /* Initialise scheduler values */
     rmt := &RemoteScheduler{
-        tr: TR,
+        config: config,
+        schema: s,
+        snapshots: snapshot.NewPublisher(),
     }
+    rmt.SetThreshold(TR)
 
     /* Run metrics collection */
-    var sender <-chan *mat.Dense
-    rmt.mc, sender = metrics.New()
+    var sender <-chan *metrics.Window
+    rmt.mc, sender = metrics.New(config.Metrics, rmt.snapshots)
     log.Debug("RMT: INITIALISE METRIC COLLECTOR")
 
     /* Run fpca */
-    rmt.fp = fpca.New(sender)
+    rmt.fp = fpca.New(sender, s, config.FPCA, rmt.snapshots)
     log.Debug("RMT: INITIALISE FPCA")
 
 
@@ -109,25 +157,44 @@ func New() *RemoteScheduler {
 	return rmt
 }
 
+/* Sets the signal threshold below which the node asks for pods */
+func (rmt *RemoteScheduler) SetThreshold(tr float64) {
+    rmt.tr.Store(math.Float64bits(tr))
+    log.WithFields(log.Fields{
+        "THRESHOLD": tr,
+    }).Debug("RMT: SET THRESHOLD")
+}
+
+func (rmt *RemoteScheduler) Threshold() float64 {
+    return math.Float64frombits(rmt.tr.Load())
+}
+
 func absFunc(i, j int, v float64) (float64) {
     return math.Abs(v)
 }
 
 
-func (rmt *RemoteScheduler) JobSignal() float64 {
-    /* TODO: How to ensure that the B, U and Sigma we load are for the same
-    * timestep. Will have to use an atomic pointer that points to be U and
-    * Sigma */
-    y := rmt.mc.Y.Load()
+/* Latest Y, the pod usage behind it, U and Sigma, consistent with one another */
+func (rmt *RemoteScheduler) Snapshot() *snapshot.Snapshot {
+    return rmt.snapshots.Load()
+}
+
+/* Computes the job signal of one snapshot, returned so the signal can be traced */
+func (rmt *RemoteScheduler) JobSignal() (float64, *snapshot.Snapshot) {
+    snap := rmt.Snapshot()
+    return jobSignal(snap), snap
+}
 
-    uSigmaPair := rmt.fp.USIgma.Load()
-    u := uSigmaPair.U
-    sigma := uSigmaPair.Sigma
+func jobSignal(snap *snapshot.Snapshot) float64 {
+    y := snap.Y
+    u := snap.U
+    sigma := snap.Sigma
 
     log.WithFields(log.Fields{
         "Y" : *y,
         "U" : *u,
         "SIGMA" : *sigma,
+        "VERSION": snap.Version,
     }).Debug("RMT: CALCULATING JOB SIGNAL")
 
     var temp, p, wP mat.Dense
@@ -147,18 +214,21 @@ TODO: Change to periodic as this will reduce delay, scheduler can use the
 latest value received
 */
 func (rmt *RemoteScheduler) Schedule() {
-    ticker := time.NewTicker(time.Second)
+    ticker := time.NewTicker(rmt.config.ReportInterval)
     defer ticker.Stop()
     for {
         <-ticker.C
 		log.Debug("RMT: BEGIN POD REQUEST")
 
-        signal := rmt.JobSignal()
+        signal, snap := rmt.JobSignal()
         log.WithFields(log.Fields{
             "R" : signal,
+            "SAMPLE": snap.Sample,
+            "EPOCH":  snap.Epoch,
+            "WINDOW": snap.Window,
         }).Debug("RMT: CALCULATED JOB SIGNAL")
-        if signal < rmt.tr {
-            rmt.RequestPod(signal)
+        if signal < rmt.Threshold() {
+            rmt.SendSignal(signal, snap)
         }
 	}
 }
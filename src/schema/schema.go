@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+/* Names of the metric dimensions the collector knows how to sample */
+const (
+    CPU            = "cpu"
+    Memory         = "memory"
+    DiskBusy       = "disk_busy"
+    DiskThroughput = "disk_throughput"
+    IOWait         = "io_wait"
+    NetRecv        = "net_recv"
+    NetSent        = "net_sent"
+    NetFaults      = "net_faults"
+
+    /*
+    Pressure stall information. The plain names are the avg10 percentage of
+    time stalled, the _total names the fraction of the last interval stalled
+    taken from the change in the total counter
+    */
+    PSICPUSome          = "psi_cpu_some"
+    PSICPUSomeTotal     = "psi_cpu_some_total"
+    PSICPUFull          = "psi_cpu_full"
+    PSICPUFullTotal     = "psi_cpu_full_total"
+    PSIMemorySome       = "psi_memory_some"
+    PSIMemorySomeTotal  = "psi_memory_some_total"
+    PSIMemoryFull       = "psi_memory_full"
+    PSIMemoryFullTotal  = "psi_memory_full_total"
+    PSIIOSome           = "psi_io_some"
+    PSIIOSomeTotal      = "psi_io_some_total"
+    PSIIOFull           = "psi_io_full"
+    PSIIOFullTotal      = "psi_io_full_total"
+)
+
+/*
+Ordered list of named metric dimensions. Row i of every Y, B and U matrix
+holds dimension i, so the metrics collector, FPCA agent and aggregator must
+all agree on the same schema
+*/
+type Schema struct {
+    Dimensions []string
+}
+
+/* Schema used when none is configured */
+var Default = Schema{
+    Dimensions: []string{
+        CPU, Memory,
+        DiskBusy, DiskThroughput, IOWait,
+        NetRecv, NetSent, NetFaults,
+    },
+}
+
+/* Parses a comma separated list of dimension names */
+func Parse(s string) (Schema, error) {
+    var dims []string
+    seen := make(map[string]bool)
+    for _, name := range strings.Split(s, ",") {
+        name = strings.TrimSpace(name)
+        if name == "" {
+            continue
+        }
+        if seen[name] {
+            return Schema{}, fmt.Errorf("duplicate metric dimension %q", name)
+        }
+        seen[name] = true
+        dims = append(dims, name)
+    }
+    if len(dims) == 0 {
+        return Schema{}, fmt.Errorf("schema has no dimensions")
+    }
+    return Schema{Dimensions: dims}, nil
+}
+
+/* Number of dimensions, d */
+func (s Schema) D() int {
+    return len(s.Dimensions)
+}
+
+/* Position of the named dimension, or -1 if the schema does not have it */
+func (s Schema) Index(name string) int {
+    for i, dim := range s.Dimensions {
+        if dim == name {
+            return i
+        }
+    }
+    return -1
+}
+
+/*
+Identifies the schema on the wire. Derived from the ordered dimension names,
+so two processes agree on the version exactly when they agree on the schema
+*/
+func (s Schema) Version() uint32 {
+    h := fnv.New32a()
+    h.Write([]byte(s.String()))
+    return h.Sum32()
+}
+
+func (s Schema) String() string {
+    return strings.Join(s.Dimensions, ",")
+}
@@ -0,0 +1,108 @@
+package snapshot
+
+import (
+	"sync/atomic"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+The state a job signal is computed from: the latest sample Y and the U and
+Sigma it is evaluated against, along with the IDs that identify them. A
+Snapshot is never modified once published
+*/
+type Snapshot struct {
+    /* Incremented on every publish, of either Y or U and Sigma */
+    Version   uint64
+
+    Y         *mat.VecDense
+    /* Sequence number of the sample Y, counting from 1 */
+    Sample    uint64
+    SampledAt time.Time
+    /* Per-pod usage over the same interval as Y, nil without cgroup v2 */
+    Pods      *PodSnapshot
+
+    U         *mat.Dense
+    Sigma     *mat.DiagDense
+    /* Number of times U and Sigma have been updated */
+    Epoch     uint64
+    /* ID of the last window B merged into U and Sigma, 0 before the first */
+    Window    uint64
+}
+
+/* Resource usage of one pod over the last sample interval */
+type PodUsage struct {
+    UID string
+    /* Fraction of the node's CPU time */
+    CPU float64
+    /* Fraction of the node's memory */
+    Memory float64
+    /* Bytes per second read from and written to block devices */
+    IORead  float64
+    IOWrite float64
+}
+
+/* Usage of every pod on the node, taken alongside one Y vector */
+type PodSnapshot struct {
+    Time time.Time
+    Pods map[string]PodUsage
+}
+
+/* Sums the usage of all pods, the share of Y the pods account for */
+func (ps *PodSnapshot) Total() PodUsage {
+    var total PodUsage
+    for _, pu := range ps.Pods {
+        total.CPU += pu.CPU
+        total.Memory += pu.Memory
+        total.IORead += pu.IORead
+        total.IOWrite += pu.IOWrite
+    }
+    return total
+}
+
+/*
+Holds the latest Snapshot. The metrics collector publishes samples and the
+FPCA agent publishes subspaces; each publish copies the other half from the
+snapshot it replaces, so a reader always sees a Y and U, Sigma pair that were
+current at the same moment
+*/
+type Publisher struct {
+    latest atomic.Pointer[Snapshot]
+}
+
+func NewPublisher() *Publisher {
+    p := &Publisher{}
+    p.latest.Store(&Snapshot{})
+    return p
+}
+
+func (p *Publisher) Load() *Snapshot {
+    return p.latest.Load()
+}
+
+/* Publishes sample number seq, taken at time t, with the pod usage behind it */
+func (p *Publisher) PublishSample(seq uint64, t time.Time, y *mat.VecDense, pods *PodSnapshot) *Snapshot {
+    return p.publish(func(next *Snapshot) {
+        next.Y, next.Sample, next.SampledAt, next.Pods = y, seq, t, pods
+    })
+}
+
+/* Publishes a subspace, the result of merging window ID window */
+func (p *Publisher) PublishSubspace(epoch uint64, window uint64, u *mat.Dense, sigma *mat.DiagDense) *Snapshot {
+    return p.publish(func(next *Snapshot) {
+        next.U, next.Sigma, next.Epoch, next.Window = u, sigma, epoch, window
+    })
+}
+
+func (p *Publisher) publish(update func(*Snapshot)) *Snapshot {
+    for {
+        cur := p.latest.Load()
+        next := *cur
+        next.Version++
+        update(&next)
+        if p.latest.CompareAndSwap(cur, &next) {
+            return &next
+        }
+    }
+}